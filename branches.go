@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Branches are separate files that the project listing nests under their main file when asked for branch_data.
+// Archived branches (including merged ones) drop out of that listing, so the branches seen in earlier scans are
+// remembered in branches.json; one that has gone missing is still scanned and reported as archived.
+const (
+	branchArchived = "archived"
+	branchMerged   = "merged"
+
+	// How long an archived branch is remembered after it was last listed
+	branchMemory = 180 * 24 * time.Hour
+)
+
+type FigmaBranch struct {
+	Key          string    `json:"key"`
+	Name         string    `json:"name"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// knownBranch is a branch as last seen in a project listing
+type knownBranch struct {
+	Key          string    `json:"key"`
+	Name         string    `json:"name"`
+	LastModified time.Time `json:"last_modified"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// branchStore maps main file keys to the branches seen under them
+type branchStore struct {
+	path  string
+	Files map[string][]knownBranch `json:"files"`
+}
+
+func openBranchStore() (*branchStore, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	store := &branchStore{
+		path:  filepath.Join(configDir, "branches.json"),
+		Files: make(map[string][]knownBranch),
+	}
+	data, err := os.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, err
+	}
+	if store.Files == nil {
+		store.Files = make(map[string][]knownBranch)
+	}
+	return store, nil
+}
+
+func (s *branchStore) save() error {
+	if s == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// update records the branches listed under a main file and returns the remembered ones that are no longer listed
+func (s *branchStore) update(mainKey string, listed []FigmaBranch, now time.Time) []knownBranch {
+	if s == nil {
+		return nil
+	}
+
+	current := make(map[string]bool)
+	var kept []knownBranch
+	for _, branch := range listed {
+		current[branch.Key] = true
+		kept = append(kept, knownBranch{Key: branch.Key, Name: branch.Name, LastModified: branch.LastModified, LastSeen: now})
+	}
+
+	var archived []knownBranch
+	for _, branch := range s.Files[mainKey] {
+		if current[branch.Key] || now.Sub(branch.LastSeen) > branchMemory {
+			continue
+		}
+		archived = append(archived, branch)
+		kept = append(kept, branch)
+	}
+
+	if len(kept) == 0 {
+		delete(s.Files, mainKey)
+	} else {
+		s.Files[mainKey] = kept
+	}
+	return archived
+}
+
+// forget drops a remembered branch, e.g. one that has been deleted
+func (s *branchStore) forget(mainKey, branchKey string) {
+	if s == nil {
+		return
+	}
+	var kept []knownBranch
+	for _, branch := range s.Files[mainKey] {
+		if branch.Key != branchKey {
+			kept = append(kept, branch)
+		}
+	}
+	if len(kept) == 0 {
+		delete(s.Files, mainKey)
+	} else {
+		s.Files[mainKey] = kept
+	}
+}
+
+// mergedInto reports whether one of the main file's versions records merging the named branch,
+// i.e. says "Merged changes from <name>" or "Merged from <name>" where the name doesn't run on into a longer one
+func mergedInto(branchName string, mainVersions []FigmaVersion) bool {
+	name := strings.TrimSpace(branchName)
+	if name == "" {
+		return false
+	}
+	merged := regexp.MustCompile(`(?i)(?:^|[^\pL\pN_])merged (?:changes )?from "?` + regexp.QuoteMeta(name) + `(?:$|[^\pL\pN_-])`)
+	for _, version := range mainVersions {
+		if merged.MatchString(version.Label) || merged.MatchString(version.Description) {
+			return true
+		}
+	}
+	return false
+}
+
+// nestBranches moves branch activity under its main file. A main file without activity of its own
+// still gets an entry, carrying only its branches.
+func nestBranches(files []FileActivity, mainVersions map[string][]FigmaVersion) []FileActivity {
+	mains := make(map[string]int)
+	var nested []FileActivity
+	for _, file := range files {
+		if file.MainFileKey == "" {
+			mains[file.FileKey] = len(nested)
+			nested = append(nested, file)
+			continue
+		}
+
+		if file.BranchStatus == branchArchived && mergedInto(file.FileName, mainVersions[file.MainFileKey]) {
+			file.BranchStatus = branchMerged
+		}
+
+		i, ok := mains[file.MainFileKey]
+		if !ok {
+			i = len(nested)
+			mains[file.MainFileKey] = i
+			nested = append(nested, FileActivity{
+				FileKey:     file.MainFileKey,
+				FileName:    file.MainFileName,
+				ProjectName: file.ProjectName,
+				TeamID:      file.TeamID,
+				TeamName:    file.TeamName,
+			})
+		}
+		nested[i].Branches = append(nested[i].Branches, file)
+	}
+	return nested
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestMergedInto(t *testing.T) {
+	tests := []struct {
+		name    string
+		branch  string
+		version FigmaVersion
+		want    bool
+	}{
+		{name: "merge label", branch: "feature", version: FigmaVersion{Label: "Merged changes from feature"}, want: true},
+		{name: "merge description", branch: "Checkout v2", version: FigmaVersion{Description: "Merged changes from Checkout v2"}, want: true},
+		{name: "short form", branch: "UI", version: FigmaVersion{Label: "Merged from UI"}, want: true},
+		{name: "case-insensitive", branch: "Feature", version: FigmaVersion{Label: "merged changes from FEATURE"}, want: true},
+		{name: "quoted name", branch: "feature", version: FigmaVersion{Label: `Merged changes from "feature"`}, want: true},
+		{name: "name with punctuation", branch: "v2 (wip)", version: FigmaVersion{Label: "Merged changes from v2 (wip)"}, want: true},
+		{name: "name inside other words", branch: "UI", version: FigmaVersion{Label: "Merge build fixes"}},
+		{name: "longer branch name", branch: "feature", version: FigmaVersion{Label: "Merged changes from feature-2"}},
+		{name: "mentions without merging", branch: "feature", version: FigmaVersion{Label: "feature", Description: "Merge planned"}},
+		{name: "other branch merged", branch: "feature", version: FigmaVersion{Label: "Merged changes from hotfix", Description: "feature polish"}},
+		{name: "blank branch name", branch: " ", version: FigmaVersion{Label: "Merged changes from "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergedInto(tt.branch, []FigmaVersion{tt.version}); got != tt.want {
+				t.Errorf("mergedInto(%q, %+v) = %v, want %v", tt.branch, tt.version, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// responseCache keeps Figma API responses on disk between runs.
+// Entries carry a validator (a file's last_modified or version) and are only reused while it still matches.
+type responseCache struct {
+	dir string
+}
+
+type cacheEntry struct {
+	Key       string          `json:"key"`
+	Validator string          `json:"validator,omitempty"`
+	StoredAt  time.Time       `json:"stored_at"`
+	Body      json.RawMessage `json:"body"`
+}
+
+func getCachePath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	cacheDir := filepath.Join(configDir, "cache")
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", err
+	}
+
+	return cacheDir, nil
+}
+
+func openResponseCache() (*responseCache, error) {
+	cacheDir, err := getCachePath()
+	if err != nil {
+		return nil, err
+	}
+	return &responseCache{dir: cacheDir}, nil
+}
+
+// clearCache removes every cached response
+func clearCache() error {
+	cacheDir, err := getCachePath()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(cacheDir)
+}
+
+func (c *responseCache) entryPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the cached body for key if it was stored with the same validator
+func (c *responseCache) get(key, validator string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.entryPath(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+
+	// Hash collisions are unlikely but cheap to rule out
+	if entry.Key != key || entry.Validator != validator {
+		return nil, false
+	}
+
+	return entry.Body, true
+}
+
+// put stores body for key. Failures are ignored: the cache only ever saves requests.
+func (c *responseCache) put(key, validator string, body []byte) {
+	if c == nil {
+		return
+	}
+
+	data, err := json.Marshal(cacheEntry{
+		Key:       key,
+		Validator: validator,
+		StoredAt:  time.Now(),
+		Body:      body,
+	})
+	if err != nil {
+		return
+	}
+
+	// Write to a temp file and rename so concurrent workers never read half an entry
+	tmp, err := os.CreateTemp(c.dir, "entry-*.tmp")
+	if err != nil {
+		return
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return
+	}
+	tmp.Close()
+
+	if err := os.Rename(tmp.Name(), c.entryPath(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// creationDates remembers when files were created; the date never changes once the first version is seen.
+// The in-memory copy serves repeated TUI runs; the response cache carries it across runs.
+var creationDates = struct {
+	sync.Mutex
+	byKey map[string]time.Time
+}{byKey: make(map[string]time.Time)}
+
+func (c *FigmaClient) creationDate(fileKey string) (time.Time, bool) {
+	key := c.creationKey(fileKey)
+	creationDates.Lock()
+	createdAt, ok := creationDates.byKey[key]
+	creationDates.Unlock()
+	if ok {
+		return createdAt, true
+	}
+
+	body, ok := c.Cache.get(key, "")
+	if !ok || json.Unmarshal(body, &createdAt) != nil {
+		return time.Time{}, false
+	}
+
+	creationDates.Lock()
+	creationDates.byKey[key] = createdAt
+	creationDates.Unlock()
+	return createdAt, true
+}
+
+func (c *FigmaClient) setCreationDate(fileKey string, createdAt time.Time) {
+	key := c.creationKey(fileKey)
+	creationDates.Lock()
+	creationDates.byKey[key] = createdAt
+	creationDates.Unlock()
+
+	if body, err := json.Marshal(createdAt); err == nil {
+		c.Cache.put(key, "", body)
+	}
+}
+
+// creationKey includes the API server, like response keys, so a stub server's files never borrow Figma's dates
+func (c *FigmaClient) creationKey(fileKey string) string {
+	return "created:" + c.BaseURL + "/" + fileKey
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Figma API client shared by the TUI and the CLI
+const (
+	defaultAPIBaseURL   = "https://api.figma.com"
+	defaultAPITimeout   = 30
+	defaultAPIUserAgent = "figma-beacon"
+
+	// Retry policy for 429 and 5xx responses
+	defaultMaxRetries  = 5  // attempts per request after the first one
+	defaultRetryBudget = 50 // retries shared by every request in a run
+	backoffBase        = time.Second
+	backoffMax         = time.Minute
+)
+
+// API connection settings, stored alongside the rest of config.json
+type apiSettings struct {
+	BaseURL   string `json:"api_base_url,omitempty"`
+	Timeout   int    `json:"api_timeout_seconds,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+	// Retries per request and per run; 0 means use the default
+	MaxRetries  int `json:"max_retries,omitempty"`
+	RetryBudget int `json:"retry_budget,omitempty"`
+}
+
+type FigmaClient struct {
+	BaseURL    string
+	Token      string
+	UserAgent  string
+	HTTPClient *http.Client
+	MaxRetries int
+	Cache      *responseCache // nil disables the on-disk cache
+	Auth       *oauthSession  // set after `figma-beacon login`; takes precedence over Token
+
+	// Remaining retries for this client; a client is created per report run
+	mu          sync.Mutex
+	retryBudget int
+	// Set on a 429 so every worker sharing the client waits, not just the one that was throttled
+	pausedUntil time.Time
+}
+
+type FigmaUser struct {
+	ID     string `json:"id"`
+	Handle string `json:"handle"`
+	Email  string `json:"email"`
+	ImgURL string `json:"img_url"`
+}
+
+// APIError is returned for any non-200 response from the Figma API
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	// Figma error bodies look like {"status":404,"err":"Not found"}
+	var body struct {
+		Err     string `json:"err"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(e.Body), &body) == nil {
+		if body.Err != "" {
+			return fmt.Sprintf("API error %d: %s", e.StatusCode, body.Err)
+		}
+		if body.Message != "" {
+			return fmt.Sprintf("API error %d: %s", e.StatusCode, body.Message)
+		}
+	}
+	return fmt.Sprintf("API error: %s", e.Body)
+}
+
+// isNotFound reports whether err is Figma saying the file doesn't exist (any more)
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// retryable reports whether the request may succeed if sent again
+func (e *APIError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+func NewFigmaClient(token string, settings apiSettings) *FigmaClient {
+	baseURL := settings.BaseURL
+	if baseURL == "" {
+		baseURL = defaultAPIBaseURL
+	}
+
+	timeout := settings.Timeout
+	if timeout <= 0 {
+		timeout = defaultAPITimeout
+	}
+
+	userAgent := settings.UserAgent
+	if userAgent == "" {
+		userAgent = defaultAPIUserAgent
+	}
+
+	maxRetries := settings.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = defaultMaxRetries
+	}
+
+	retryBudget := settings.RetryBudget
+	if retryBudget <= 0 {
+		retryBudget = defaultRetryBudget
+	}
+
+	return &FigmaClient{
+		BaseURL:     strings.TrimSuffix(baseURL, "/"),
+		Token:       token,
+		UserAgent:   userAgent,
+		HTTPClient:  &http.Client{Timeout: time.Duration(timeout) * time.Second},
+		MaxRetries:  maxRetries,
+		retryBudget: retryBudget,
+	}
+}
+
+// hasCredentials reports whether the client has a personal access token or an OAuth login
+func (c *FigmaClient) hasCredentials() bool {
+	return c.Token != "" || c.Auth != nil
+}
+
+// takeRetry consumes one retry from the run's budget
+func (c *FigmaClient) takeRetry() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.retryBudget <= 0 {
+		return false
+	}
+	c.retryBudget--
+	return true
+}
+
+// pause holds back all requests on this client for d
+func (c *FigmaClient) pause(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(c.pausedUntil) {
+		c.pausedUntil = until
+	}
+}
+
+// waitForPause blocks while the client is paused by a rate limit
+func (c *FigmaClient) waitForPause(ctx context.Context) error {
+	c.mu.Lock()
+	wait := time.Until(c.pausedUntil)
+	c.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	return sleepContext(ctx, wait)
+}
+
+// get performs an authenticated GET request and decodes the JSON response into out.
+// Rate-limited (429) and server error (5xx) responses are retried with backoff.
+func (c *FigmaClient) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	body, err := c.fetch(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+// getCached is get backed by the response cache. A cached body is used only while validator matches
+// the one it was stored with; an empty validator marks the response as immutable.
+// Keys include the base URL, so responses from a stub server and from Figma never mix.
+func (c *FigmaClient) getCached(ctx context.Context, path string, query url.Values, validator string, out interface{}) error {
+	key := c.BaseURL + path
+	if len(query) > 0 {
+		key += "?" + query.Encode()
+	}
+
+	if body, ok := c.Cache.get(key, validator); ok {
+		if err := json.Unmarshal(body, out); err == nil {
+			return nil
+		}
+	}
+
+	body, err := c.fetch(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return err
+	}
+
+	c.Cache.put(key, validator, body)
+	return nil
+}
+
+// fetch returns the raw body of a successful GET request, retrying as described on get
+func (c *FigmaClient) fetch(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	if !c.hasCredentials() {
+		return nil, fmt.Errorf("No Figma token set")
+	}
+
+	reqURL := c.BaseURL + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForPause(ctx); err != nil {
+			return nil, err
+		}
+
+		body, retryAfter, err := c.do(ctx, reqURL)
+		if err == nil {
+			return body, nil
+		}
+
+		apiErr, ok := err.(*APIError)
+		if !ok || !apiErr.retryable() {
+			return nil, err
+		}
+		// Waiting longer than any backoff would leave the CLI or TUI hanging silently, so say so and stop
+		if retryAfter > backoffMax {
+			return nil, fmt.Errorf("Figma asked to wait %s before retrying, giving up: %w", retryAfter.Round(time.Second), err)
+		}
+		if attempt >= c.MaxRetries || !c.takeRetry() {
+			return nil, fmt.Errorf("gave up after %d attempts: %w", attempt+1, err)
+		}
+
+		delay := retryDelay(attempt, retryAfter)
+		if apiErr.StatusCode == http.StatusTooManyRequests {
+			// The limit applies to the token, so slow down every worker
+			c.pause(delay)
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// do sends a single request and returns the body of a 200 response.
+// For any other status it returns an *APIError and the server's Retry-After hint, if any.
+func (c *FigmaClient) do(ctx context.Context, reqURL string) ([]byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.Auth != nil {
+		token, err := c.Auth.accessToken(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else {
+		req.Header.Set("X-Figma-Token", c.Token)
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseRetryAfter(resp.Header.Get("Retry-After")), &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	return body, 0, nil
+}
+
+// parseRetryAfter accepts both forms of the header: delay in seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// retryDelay honors Retry-After when present, otherwise backs off exponentially with jitter
+func retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	delay := backoffBase << attempt
+	if delay > backoffMax || delay <= 0 {
+		delay = backoffMax
+	}
+
+	// Full jitter in the upper half keeps concurrent retries from lining up
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Me returns the user that owns the token
+func (c *FigmaClient) Me(ctx context.Context) (*FigmaUser, error) {
+	var user FigmaUser
+	if err := c.get(ctx, "/v1/me", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (c *FigmaClient) TeamProjects(ctx context.Context, teamID string) ([]FigmaProject, error) {
+	var result struct {
+		Name     string         `json:"name"`
+		Projects []FigmaProject `json:"projects"`
+	}
+	path := fmt.Sprintf("/v1/teams/%s/projects", url.PathEscape(teamID))
+	if err := c.get(ctx, path, nil, &result); err != nil {
+		return nil, err
+	}
+	for i := range result.Projects {
+		result.Projects[i].TeamID = teamID
+		result.Projects[i].TeamName = result.Name
+	}
+	return result.Projects, nil
+}
+
+func (c *FigmaClient) ProjectFiles(ctx context.Context, projectID string) ([]FigmaFile, error) {
+	var result struct {
+		Files []FigmaFile `json:"files"`
+	}
+	path := fmt.Sprintf("/v1/projects/%s/files", url.PathEscape(projectID))
+	// Branches are only listed on request
+	query := url.Values{"branch_data": {"true"}}
+	if err := c.get(ctx, path, query, &result); err != nil {
+		return nil, err
+	}
+	for i := range result.Files {
+		result.Files[i].ProjectID = projectID
+	}
+	return result.Files, nil
+}
+
+// FileMeta returns name, lastModified and version for a file.
+// depth=1 stops the API from sending the document tree below the pages, which is all we would discard anyway.
+// listed is the file's last_modified from the project listing: while it is unchanged a cached response is reused.
+func (c *FigmaClient) FileMeta(ctx context.Context, fileKey string, listed time.Time) (*FigmaFileMetadata, error) {
+	var meta FigmaFileMetadata
+	path := fmt.Sprintf("/v1/files/%s", url.PathEscape(fileKey))
+	query := url.Values{"depth": {"1"}}
+
+	var err error
+	if listed.IsZero() {
+		err = c.get(ctx, path, query, &meta)
+	} else {
+		err = c.getCached(ctx, path, query, listed.UTC().Format(time.RFC3339Nano), &meta)
+	}
+	if err != nil {
+		return nil, err
+	}
+	meta.Key = fileKey
+	return &meta, nil
+}
+
+// versionsPageSize is the largest page the versions endpoint accepts
+const versionsPageSize = 50
+
+// FileVersions returns one page of a file's version history, newest first.
+// Pass the before cursor from the previous page to continue; an empty next cursor means the oldest version was reached.
+// Pages behind a cursor never change and are cached as-is; the first page is cached against fileVersion.
+func (c *FigmaClient) FileVersions(ctx context.Context, fileKey, before, fileVersion string) (versions []FigmaVersion, next string, err error) {
+	var result struct {
+		Versions   []FigmaVersion `json:"versions"`
+		Pagination struct {
+			NextPage string `json:"next_page"`
+		} `json:"pagination"`
+	}
+
+	query := url.Values{"page_size": {strconv.Itoa(versionsPageSize)}}
+	if before != "" {
+		query.Set("before", before)
+	}
+
+	path := fmt.Sprintf("/v1/files/%s/versions", url.PathEscape(fileKey))
+	switch {
+	case before != "":
+		err = c.getCached(ctx, path, query, "", &result)
+	case fileVersion != "":
+		err = c.getCached(ctx, path, query, "version:"+fileVersion, &result)
+	default:
+		err = c.get(ctx, path, query, &result)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	// next_page is a full URL; only its cursor is needed since requests always go through BaseURL
+	if result.Pagination.NextPage != "" {
+		if nextURL, err := url.Parse(result.Pagination.NextPage); err == nil {
+			next = nextURL.Query().Get("before")
+		}
+	}
+	return result.Versions, next, nil
+}
+
+// FileHistory follows version pages from newest to oldest.
+// It stops early once a page reaches versions older than since; complete reports whether the oldest version was reached.
+// A zero since walks the whole history.
+func (c *FigmaClient) FileHistory(ctx context.Context, fileKey string, since time.Time, fileVersion string) (versions []FigmaVersion, complete bool, err error) {
+	before := ""
+	for {
+		page, next, err := c.FileVersions(ctx, fileKey, before, fileVersion)
+		if err != nil {
+			return nil, false, err
+		}
+		versions = append(versions, page...)
+
+		if next == "" || next == before || len(page) == 0 {
+			return versions, true, nil
+		}
+		if !since.IsZero() && page[len(page)-1].Created.Before(since) {
+			return versions, false, nil
+		}
+		before = next
+	}
+}
+
+func (c *FigmaClient) FileComments(ctx context.Context, fileKey string) ([]FigmaComment, error) {
+	var result struct {
+		Comments []FigmaComment `json:"comments"`
+	}
+	path := fmt.Sprintf("/v1/files/%s/comments", url.PathEscape(fileKey))
+	if err := c.get(ctx, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Comments, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value    string
+		min, max time.Duration
+	}{
+		{value: "", min: 0, max: 0},
+		{value: "7", min: 7 * time.Second, max: 7 * time.Second},
+		{value: " 2 ", min: 2 * time.Second, max: 2 * time.Second},
+		{value: "0", min: 0, max: 0},
+		{value: "-5", min: 0, max: 0},
+		{value: "soon", min: 0, max: 0},
+		{value: time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat), min: 28 * time.Second, max: 30 * time.Second},
+		{value: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), min: 0, max: 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got < tt.min || got > tt.max {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v to %v", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	if got := retryDelay(3, 12*time.Second); got != 12*time.Second {
+		t.Errorf("retryDelay with Retry-After = %v, want the server's 12s", got)
+	}
+
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{attempt: 0, ceiling: backoffBase},
+		{attempt: 2, ceiling: 4 * backoffBase},
+		{attempt: 10, ceiling: backoffMax},
+		{attempt: 70, ceiling: backoffMax}, // the shift overflows
+	}
+	for _, tt := range tests {
+		for range 20 {
+			// Jitter stays in the upper half of the backoff
+			if got := retryDelay(tt.attempt, 0); got < tt.ceiling/2 || got > tt.ceiling {
+				t.Fatalf("retryDelay(%d) = %v, want %v to %v", tt.attempt, got, tt.ceiling/2, tt.ceiling)
+			}
+		}
+	}
+}
+
+// flakyServer answers with status and headers until it has failed failures times, then with {}
+func flakyServer(t *testing.T, failures int64, status int, header http.Header) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var requests atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= failures {
+			for name, values := range header {
+				w.Header()[name] = values
+			}
+			w.WriteHeader(status)
+			io.WriteString(w, `{"err": "Not now"}`)
+			return
+		}
+		io.WriteString(w, `{}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestFetchRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int64
+		status   int
+		header   http.Header
+		settings apiSettings
+		wantErr  string
+		requests int64
+		minWait  time.Duration
+	}{
+		{
+			name:     "waits out Retry-After",
+			failures: 1,
+			status:   http.StatusTooManyRequests,
+			header:   http.Header{"Retry-After": {"1"}},
+			requests: 2,
+			minWait:  time.Second,
+		},
+		{
+			name:     "gives up on a long Retry-After",
+			failures: 1,
+			status:   http.StatusTooManyRequests,
+			header:   http.Header{"Retry-After": {"120"}},
+			wantErr:  "asked to wait 2m0s before retrying, giving up",
+			requests: 1,
+		},
+		{
+			name:     "stops when the run's budget is spent",
+			failures: 10,
+			status:   http.StatusTooManyRequests,
+			header:   http.Header{"Retry-After": {"1"}},
+			settings: apiSettings{RetryBudget: 1},
+			wantErr:  "gave up after 2 attempts",
+			requests: 2,
+		},
+		{
+			name:     "doesn't retry client errors",
+			failures: 1,
+			status:   http.StatusForbidden,
+			wantErr:  "API error 403",
+			requests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := flakyServer(t, tt.failures, tt.status, tt.header)
+			tt.settings.BaseURL = server.URL
+			client := NewFigmaClient("figd_test", tt.settings)
+
+			start := time.Now()
+			_, err := client.fetch(context.Background(), "/v1/me", nil)
+			elapsed := time.Since(start)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("server saw %d requests, want %d", got, tt.requests)
+			}
+			if elapsed < tt.minWait {
+				t.Errorf("returned after %v, before Retry-After ran out", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Named contexts let one install hold several Figma accounts. The top-level account fields of config.json
+// are the "default" context, so configs from before contexts existed keep working unchanged.
+const (
+	defaultContextName = "default"
+	envFigmaContext    = "FIGMA_CONTEXT"
+)
+
+// contextSettings is one account. Tokens are moved to the credential store like the top-level ones.
+type contextSettings struct {
+	UserID     string      `json:"user_id,omitempty"`
+	UserHandle string      `json:"user_handle,omitempty"`
+	UserEmail  string      `json:"user_email,omitempty"`
+	TeamID     string      `json:"team_id,omitempty"`
+	FigmaToken string      `json:"figma_token,omitempty"`
+	OAuth      *oauthToken `json:"oauth,omitempty"`
+}
+
+// contextCredentials are a context's secrets, as kept in the credential store
+type contextCredentials struct {
+	FigmaToken string      `json:"figma_token,omitempty"`
+	OAuth      *oauthToken `json:"oauth,omitempty"`
+}
+
+// normalizeContext maps the empty name used by profiles and configs from before contexts to "default"
+func normalizeContext(name string) string {
+	if name == "" {
+		return defaultContextName
+	}
+	return name
+}
+
+// activeContextName picks the context: flag, then FIGMA_CONTEXT, then `context use`, then default
+func activeContextName(cfg config, flagValue string) (string, string) {
+	if flagValue != "" {
+		return flagValue, "flag -context"
+	}
+	if env := os.Getenv(envFigmaContext); env != "" {
+		return env, "env " + envFigmaContext
+	}
+	if cfg.CurrentContext != "" {
+		return cfg.CurrentContext, sourceFile
+	}
+	return defaultContextName, sourceDefault
+}
+
+// contextNames lists default first, then the named contexts alphabetically
+func (cfg config) contextNames() []string {
+	names := []string{defaultContextName}
+	var others []string
+	for name := range cfg.Contexts {
+		if name != defaultContextName {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// withContext returns cfg with the named context's account in the top-level fields
+func (cfg config) withContext(name string) (config, error) {
+	name = normalizeContext(name)
+	if name == defaultContextName {
+		return cfg, nil
+	}
+
+	ctx, ok := cfg.Contexts[name]
+	if !ok {
+		return cfg, fmt.Errorf("context '%s' not found. Run 'figma-beacon context list'", name)
+	}
+
+	cfg.UserID = ctx.UserID
+	cfg.UserHandle = ctx.UserHandle
+	cfg.UserEmail = ctx.UserEmail
+	cfg.TeamID = ctx.TeamID
+	cfg.FigmaToken = ctx.FigmaToken
+	cfg.OAuth = ctx.OAuth
+	return cfg, nil
+}
+
+// storeContext writes the top-level account fields of active back into the named context of cfg
+func (cfg *config) storeContext(name string, active config) {
+	name = normalizeContext(name)
+	if name == defaultContextName {
+		cfg.UserID = active.UserID
+		cfg.UserHandle = active.UserHandle
+		cfg.UserEmail = active.UserEmail
+		cfg.TeamID = active.TeamID
+		cfg.FigmaToken = active.FigmaToken
+		cfg.OAuth = active.OAuth
+		return
+	}
+
+	contexts := make(map[string]contextSettings, len(cfg.Contexts)+1)
+	for key, value := range cfg.Contexts {
+		contexts[key] = value
+	}
+	contexts[name] = contextSettings{
+		UserID:     active.UserID,
+		UserHandle: active.UserHandle,
+		UserEmail:  active.UserEmail,
+		TeamID:     active.TeamID,
+		FigmaToken: active.FigmaToken,
+		OAuth:      active.OAuth,
+	}
+	cfg.Contexts = contexts
+}
+
+// loadContextProfiles returns the profiles created in the given context
+func loadContextProfiles(contextName string) ([]Profile, error) {
+	profiles, err := loadAllProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	contextName = normalizeContext(contextName)
+	var kept []Profile
+	for _, profile := range profiles {
+		if normalizeContext(profile.Context) == contextName {
+			kept = append(kept, profile)
+		}
+	}
+	return kept, nil
+}
+
+// profileContext is the value stored in Profile.Context; the default context is left empty
+// so default profiles look exactly like ones saved before contexts existed
+func profileContext(contextName string) string {
+	if normalizeContext(contextName) == defaultContextName {
+		return ""
+	}
+	return contextName
+}
+
+// runContextCommand implements `context list`, `context use <name>` and `context add <name>`
+func runContextCommand(args []string) error {
+	usage := fmt.Errorf("usage: figma-beacon context list | use <name> | add <name>")
+	if len(args) == 0 {
+		return usage
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	switch args[0] {
+	case "list":
+		current, _ := activeContextName(cfg, "")
+		for _, name := range cfg.contextNames() {
+			account, _ := cfg.withContext(name)
+			marker := " "
+			if name == current {
+				marker = "*"
+			}
+			user := account.UserHandle
+			if user == "" {
+				user = account.UserID
+			}
+			if user == "" {
+				user = "(no user)"
+			}
+			team := account.TeamID
+			if team == "" {
+				team = "(no team)"
+			}
+			fmt.Printf("%s %-16s %s, team %s\n", marker, name, user, team)
+		}
+		return nil
+
+	case "use":
+		if len(args) != 2 {
+			return usage
+		}
+		name := args[1]
+		if _, err := cfg.withContext(name); err != nil {
+			return err
+		}
+		cfg.CurrentContext = name
+		if name == defaultContextName {
+			cfg.CurrentContext = ""
+		}
+		if err := saveConfig(cfg); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Switched to context '%s'\n", name)
+		return nil
+
+	case "add":
+		if len(args) != 2 {
+			return usage
+		}
+		name := args[1]
+		if name == defaultContextName || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("'%s' can't be used as a context name", name)
+		}
+		if _, exists := cfg.Contexts[name]; exists {
+			return fmt.Errorf("context '%s' already exists", name)
+		}
+
+		// The token is read from stdin so it never shows up in shell history or process lists
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Fprintf(os.Stderr, "Figma personal access token for '%s' (leave empty to set it later): ", name)
+		token, _ := reader.ReadString('\n')
+		token = strings.TrimSpace(token)
+		fmt.Fprintf(os.Stderr, "Team ID (optional): ")
+		teamID, _ := reader.ReadString('\n')
+
+		account := config{FigmaToken: token, TeamID: strings.TrimSpace(teamID)}
+		if token != "" {
+			client := NewFigmaClient(token, cfg.apiSettings)
+			user, err := client.Me(context.Background())
+			if err != nil {
+				return fmt.Errorf("failed to fetch user info: %w", err)
+			}
+			account.UserID = user.ID
+			account.UserHandle = user.Handle
+			account.UserEmail = user.Email
+		}
+
+		cfg.storeContext(name, account)
+		if err := saveConfig(cfg); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+
+		fmt.Fprintf(os.Stderr, "Added context '%s'", name)
+		if account.UserHandle != "" {
+			fmt.Fprintf(os.Stderr, " for %s", account.UserHandle)
+		}
+		fmt.Fprintf(os.Stderr, ". Switch to it with: figma-beacon context use %s\n", name)
+		return nil
+
+	default:
+		return usage
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// Credentials are kept out of config.json. They are saved to the first available backend:
+// the OS keyring (Secret Service), then a passphrase-encrypted file, then a file only the user can read.
+const (
+	credentialStoreAuto      = "auto"
+	credentialStoreKeyring   = "keyring"
+	credentialStoreEncrypted = "encrypted"
+	credentialStoreFile      = "file"
+
+	// Environment variable holding the passphrase for the encrypted file backend
+	passphraseEnv = "FIGMA_BEACON_PASSPHRASE"
+
+	pbkdf2Iterations = 600000
+)
+
+var errNoCredentials = errors.New("no credentials stored")
+
+// credentials holds every secret figma-beacon knows about
+type credentials struct {
+	FigmaToken        string      `json:"figma_token,omitempty"`
+	OAuth             *oauthToken `json:"oauth,omitempty"`
+	OAuthClientSecret string      `json:"oauth_client_secret,omitempty"`
+
+	Contexts map[string]contextCredentials `json:"contexts,omitempty"` // Secrets of the named contexts
+}
+
+func (c credentials) empty() bool {
+	return c.FigmaToken == "" && c.OAuth == nil && c.OAuthClientSecret == "" && len(c.Contexts) == 0
+}
+
+// credentialBackend is a place credentials can be kept. load returns errNoCredentials when nothing is stored.
+type credentialBackend interface {
+	name() string
+	available() bool
+	load() ([]byte, error)
+	save(data []byte) error
+	remove() error
+}
+
+// credentialBackends lists every backend in order of preference
+func credentialBackends() ([]credentialBackend, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return []credentialBackend{
+		keyringBackend{},
+		encryptedFileBackend{path: filepath.Join(configDir, "credentials.enc")},
+		plainFileBackend{path: filepath.Join(configDir, "credentials.json")},
+	}, nil
+}
+
+// credentialTarget is the backend credentials are saved to: the one the config asks for,
+// or with "auto" the most preferred one available
+func credentialTarget(preference string) (credentialBackend, error) {
+	backends, err := credentialBackends()
+	if err != nil {
+		return nil, err
+	}
+
+	switch preference {
+	case "", credentialStoreAuto:
+		for _, backend := range backends {
+			if backend.available() {
+				return backend, nil
+			}
+		}
+	case credentialStoreKeyring, credentialStoreEncrypted, credentialStoreFile:
+		for _, backend := range backends {
+			if backend.name() == preference {
+				if !backend.available() {
+					return nil, fmt.Errorf("credential store '%s' is not available", preference)
+				}
+				return backend, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown credential_store '%s' (use auto, keyring, encrypted or file)", preference)
+}
+
+// loadCredentials reads credentials from the first backend that has them, whatever the preference,
+// so switching credential_store still finds the old copy. It also returns the backend's name.
+func loadCredentials() (credentials, string, error) {
+	var creds credentials
+
+	backends, err := credentialBackends()
+	if err != nil {
+		return creds, "", err
+	}
+
+	for _, backend := range backends {
+		data, err := backend.load()
+		if errors.Is(err, errNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return creds, backend.name(), fmt.Errorf("failed to read credentials from %s: %w", backend.name(), err)
+		}
+		if err := json.Unmarshal(data, &creds); err != nil {
+			return creds, backend.name(), fmt.Errorf("failed to read credentials from %s: %w", backend.name(), err)
+		}
+		return creds, backend.name(), nil
+	}
+
+	return creds, "", nil
+}
+
+// saveCredentials writes creds to the target backend and removes copies from the others,
+// so a plaintext file doesn't linger once the keyring or a passphrase becomes available.
+func saveCredentials(preference string, creds credentials) error {
+	// Refuse to overwrite credentials that exist but can't be read, e.g. an encrypted file without its passphrase
+	if _, _, err := loadCredentials(); err != nil {
+		return err
+	}
+
+	backends, err := credentialBackends()
+	if err != nil {
+		return err
+	}
+	target, err := credentialTarget(preference)
+	if err != nil {
+		return err
+	}
+
+	if !creds.empty() {
+		data, err := json.Marshal(creds)
+		if err != nil {
+			return err
+		}
+		// The TUI saves often; skip the write when nothing changed
+		if current, err := target.load(); err != nil || !bytes.Equal(current, data) {
+			if err := target.save(data); err != nil {
+				return fmt.Errorf("failed to save credentials to %s: %w", target.name(), err)
+			}
+		}
+	}
+
+	for _, backend := range backends {
+		if creds.empty() || backend.name() != target.name() {
+			if err := backend.remove(); err != nil {
+				return fmt.Errorf("failed to remove credentials from %s: %w", backend.name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// keyringBackend uses the desktop keyring through libsecret's secret-tool
+type keyringBackend struct{}
+
+var keyringAttributes = []string{"service", "figma-beacon", "account", "credentials"}
+
+func (keyringBackend) name() string { return credentialStoreKeyring }
+
+func (keyringBackend) available() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" {
+		return false
+	}
+	_, err := exec.LookPath("secret-tool")
+	return err == nil
+}
+
+func (k keyringBackend) load() ([]byte, error) {
+	if !k.available() {
+		return nil, errNoCredentials
+	}
+
+	out, err := exec.Command("secret-tool", append([]string{"lookup"}, keyringAttributes...)...).Output()
+	// secret-tool exits non-zero with no output when nothing matches
+	if len(out) == 0 {
+		return nil, errNoCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (k keyringBackend) save(data []byte) error {
+	args := append([]string{"store", "--label=Figma Beacon credentials"}, keyringAttributes...)
+	cmd := exec.Command("secret-tool", args...)
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func (k keyringBackend) remove() error {
+	if !k.available() {
+		return nil
+	}
+	// Clearing an absent item isn't an error worth reporting
+	exec.Command("secret-tool", append([]string{"clear"}, keyringAttributes...)...).Run()
+	return nil
+}
+
+// encryptedFileBackend seals credentials with AES-256-GCM under a key derived from FIGMA_BEACON_PASSPHRASE
+type encryptedFileBackend struct {
+	path string
+}
+
+type encryptedCredentials struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Key derivation is deliberately slow and config is loaded often, so remember the last file decrypted
+// and the passphrase that opened it
+var lastDecrypted = struct {
+	sync.Mutex
+	sealed     string
+	passphrase string
+	plain      []byte
+}{}
+
+func (encryptedFileBackend) name() string { return credentialStoreEncrypted }
+
+func (encryptedFileBackend) available() bool {
+	return os.Getenv(passphraseEnv) != ""
+}
+
+func (e encryptedFileBackend) load() ([]byte, error) {
+	data, err := os.ReadFile(e.path)
+	if os.IsNotExist(err) {
+		return nil, errNoCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !e.available() {
+		return nil, fmt.Errorf("credentials are encrypted; set %s", passphraseEnv)
+	}
+
+	lastDecrypted.Lock()
+	defer lastDecrypted.Unlock()
+	if lastDecrypted.sealed == string(data) && lastDecrypted.passphrase == os.Getenv(passphraseEnv) {
+		return lastDecrypted.plain, nil
+	}
+
+	var sealed encryptedCredentials
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, err
+	}
+
+	gcm, err := credentialCipher(sealed.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, sealed.Nonce, sealed.Ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("wrong passphrase or corrupted file")
+	}
+
+	lastDecrypted.sealed = string(data)
+	lastDecrypted.passphrase = os.Getenv(passphraseEnv)
+	lastDecrypted.plain = plain
+	return plain, nil
+}
+
+func (e encryptedFileBackend) save(data []byte) error {
+	sealed := encryptedCredentials{
+		Salt: make([]byte, 16),
+	}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return err
+	}
+
+	gcm, err := credentialCipher(sealed.Salt)
+	if err != nil {
+		return err
+	}
+	sealed.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return err
+	}
+	sealed.Ciphertext = gcm.Seal(nil, sealed.Nonce, data, nil)
+
+	out, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writePrivateFile(e.path, out)
+}
+
+func (e encryptedFileBackend) remove() error {
+	if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func credentialCipher(salt []byte) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, os.Getenv(passphraseEnv), salt, pbkdf2Iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// plainFileBackend is the last resort: a JSON file readable only by the user
+type plainFileBackend struct {
+	path string
+}
+
+func (plainFileBackend) name() string { return credentialStoreFile }
+
+func (plainFileBackend) available() bool { return true }
+
+func (p plainFileBackend) load() ([]byte, error) {
+	data, err := os.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return nil, errNoCredentials
+	}
+	return data, err
+}
+
+func (p plainFileBackend) save(data []byte) error {
+	return writePrivateFile(p.path, data)
+}
+
+func (p plainFileBackend) remove() error {
+	if err := os.Remove(p.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writePrivateFile writes data with mode 0600, tightening the mode of an existing file too
+func writePrivateFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
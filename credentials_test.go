@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// credentialHome points figma-beacon at an empty directory, with no keyring and no passphrase
+func credentialHome(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(envBeaconHome, dir)
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "")
+	t.Setenv(passphraseEnv, "")
+	return dir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fileMode(t *testing.T, path string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Mode().Perm()
+}
+
+func TestLoadConfigMovesInlineTokenToFile(t *testing.T) {
+	dir := credentialHome(t)
+	configPath := filepath.Join(dir, "config.json")
+	writeTestFile(t, configPath, `{"figma_token": "figd_secret", "user_id": "u1", "team_id": "t1"}`)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.FigmaToken != "figd_secret" || cfg.TeamID != "t1" {
+		t.Errorf("loaded config = %+v", cfg)
+	}
+
+	credentialsPath := filepath.Join(dir, "credentials.json")
+	if mode := fileMode(t, credentialsPath); mode != 0600 {
+		t.Errorf("credentials.json mode = %v, want 0600", mode)
+	}
+	data, err := os.ReadFile(credentialsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored credentials
+	if err := json.Unmarshal(data, &stored); err != nil || stored.FigmaToken != "figd_secret" {
+		t.Errorf("credentials.json = %s (%v)", data, err)
+	}
+
+	data, err = os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "figd_secret") || strings.Contains(string(data), "figma_token") {
+		t.Errorf("config.json still holds the token: %s", data)
+	}
+	if !strings.Contains(string(data), `"team_id": "t1"`) {
+		t.Errorf("config.json lost its settings: %s", data)
+	}
+
+	// The token is now read back from the store
+	if cfg, err := loadConfig(); err != nil || cfg.FigmaToken != "figd_secret" {
+		t.Errorf("reloaded token = %q, %v", cfg.FigmaToken, err)
+	}
+}
+
+func TestLoadConfigTightensCredentialFileMode(t *testing.T) {
+	dir := credentialHome(t)
+	credentialsPath := filepath.Join(dir, "credentials.json")
+	writeTestFile(t, credentialsPath, `{"figma_token": "figd_old"}`)
+
+	// A new inline token is newer than the stored one and rewrites the file
+	writeTestFile(t, filepath.Join(dir, "config.json"), `{"figma_token": "figd_new"}`)
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.FigmaToken != "figd_new" {
+		t.Errorf("token = %q, want the one from config.json", cfg.FigmaToken)
+	}
+	if mode := fileMode(t, credentialsPath); mode != 0600 {
+		t.Errorf("credentials.json mode = %v, want 0600", mode)
+	}
+}
+
+func TestEncryptedCredentials(t *testing.T) {
+	dir := credentialHome(t)
+	t.Setenv(passphraseEnv, "correct horse")
+	encryptedPath := filepath.Join(dir, "credentials.enc")
+
+	if err := saveCredentials(credentialStoreEncrypted, credentials{FigmaToken: "figd_secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if mode := fileMode(t, encryptedPath); mode != 0600 {
+		t.Errorf("credentials.enc mode = %v, want 0600", mode)
+	}
+	sealed, err := os.ReadFile(encryptedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(sealed), "figd_secret") {
+		t.Errorf("credentials.enc holds the token in plain text")
+	}
+
+	creds, source, err := loadCredentials()
+	if err != nil || creds.FigmaToken != "figd_secret" || source != credentialStoreEncrypted {
+		t.Fatalf("round trip = %+v from %q, %v", creds, source, err)
+	}
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		t.Setenv(passphraseEnv, "wrong")
+		if _, _, err := loadCredentials(); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+			t.Errorf("load error = %v, want a wrong passphrase", err)
+		}
+		// Saving would lose the sealed token, so it is refused
+		if err := saveCredentials(credentialStoreFile, credentials{FigmaToken: "figd_other"}); err == nil {
+			t.Errorf("saved over credentials that couldn't be read")
+		}
+		if _, err := os.Stat(filepath.Join(dir, "credentials.json")); !os.IsNotExist(err) {
+			t.Errorf("credentials.json was written: %v", err)
+		}
+	})
+
+	t.Run("no passphrase", func(t *testing.T) {
+		t.Setenv(passphraseEnv, "")
+		if _, _, err := loadCredentials(); err == nil || !strings.Contains(err.Error(), passphraseEnv) {
+			t.Errorf("load error = %v, want a hint to set %s", err, passphraseEnv)
+		}
+		if err := saveCredentials(credentialStoreAuto, credentials{FigmaToken: "figd_other"}); err == nil {
+			t.Errorf("saved over credentials that couldn't be read")
+		}
+	})
+
+	if after, err := os.ReadFile(encryptedPath); err != nil || string(after) != string(sealed) {
+		t.Errorf("credentials.enc changed after refused saves (%v)", err)
+	}
+}
+
+func TestSaveCredentialsRemovesOtherCopies(t *testing.T) {
+	dir := credentialHome(t)
+	plainPath := filepath.Join(dir, "credentials.json")
+	writeTestFile(t, plainPath, `{"figma_token": "figd_secret"}`)
+
+	// Once a passphrase is set, loading moves the plaintext copy into the encrypted file
+	t.Setenv(passphraseEnv, "correct horse")
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.FigmaToken != "figd_secret" {
+		t.Errorf("token = %q", cfg.FigmaToken)
+	}
+	if _, err := os.Stat(plainPath); !os.IsNotExist(err) {
+		t.Errorf("plaintext credentials.json left behind: %v", err)
+	}
+	if _, source, err := loadCredentials(); err != nil || source != credentialStoreEncrypted {
+		t.Errorf("credentials now in %q, %v; want encrypted", source, err)
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables that override config.json, for CI runners and containers.
+// Precedence is always flag > environment > config file > default.
+const (
+	envFigmaToken  = "FIGMA_TOKEN"
+	envFigmaTeamID = "FIGMA_TEAM_ID"
+	envFigmaUserID = "FIGMA_USER_ID"
+	envBeaconHome  = "FIGMA_BEACON_HOME" // the figma-beacon directory itself, holding config.json, profiles/ and cache/
+	envXDGConfig   = "XDG_CONFIG_HOME"
+)
+
+// Sources reported by `config show --resolved`
+const (
+	sourceDefault = "default"
+	sourceFile    = "config file"
+	sourceStore   = "credential store"
+)
+
+// configBaseDir locates the figma-beacon directory and says why it is there
+func configBaseDir() (string, string, error) {
+	if dir := os.Getenv(envBeaconHome); dir != "" {
+		return dir, "env " + envBeaconHome, nil
+	}
+	// The XDG spec says relative paths are invalid and must be ignored
+	if xdg := os.Getenv(envXDGConfig); xdg != "" && filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "figma-beacon"), "env " + envXDGConfig, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(homeDir, ".config", "figma-beacon"), sourceDefault, nil
+}
+
+// resolvedConfig is the effective configuration: config.json and the credential store with environment overrides applied.
+// It must never be saved, or environment values would end up in the config file.
+type resolvedConfig struct {
+	config
+	context string            // the active context, whose account is in the top-level fields
+	sources map[string]string // keyed by config.json key
+}
+
+func (r resolvedConfig) source(key string) string {
+	if source, ok := r.sources[key]; ok {
+		return source
+	}
+	return sourceDefault
+}
+
+// resolveConfig loads the config, switches to the active context (contextFlag, if set, names it) and applies environment overrides
+func resolveConfig(contextFlag string) (resolvedConfig, error) {
+	cfg, err := loadConfig()
+	resolved := resolvedConfig{config: cfg, context: defaultContextName, sources: make(map[string]string)}
+	if err != nil {
+		return resolved, err
+	}
+
+	// An unknown context is reported once the environment overrides are applied, and the default one is used meanwhile
+	contextName, contextSource := activeContextName(cfg, contextFlag)
+	cfg, contextErr := cfg.withContext(contextName)
+	if contextErr != nil {
+		contextName = defaultContextName
+	} else {
+		resolved.sources["current_context"] = contextSource
+	}
+	resolved.config = cfg
+	resolved.context = normalizeContext(contextName)
+
+	// Account values of a named context come from its entry under "contexts"
+	accountSource := sourceFile
+	storeSource := sourceStore
+	if contextName != defaultContextName {
+		accountSource = "context " + contextName
+		storeSource = sourceStore + ", context " + contextName
+	}
+
+	fileValues := map[string]bool{
+		"user_id":             cfg.UserID != "",
+		"team_id":             cfg.TeamID != "",
+		"user_handle":         cfg.UserHandle != "",
+		"user_email":          cfg.UserEmail != "",
+		"concurrency":         cfg.Concurrency > 0,
+		"timezone":            cfg.Timezone != "",
+		"credential_store":    cfg.CredentialStore != "",
+		"api_base_url":        cfg.BaseURL != "",
+		"api_timeout_seconds": cfg.Timeout > 0,
+		"user_agent":          cfg.UserAgent != "",
+		"max_retries":         cfg.MaxRetries > 0,
+		"retry_budget":        cfg.RetryBudget > 0,
+		"oauth_client_id":     cfg.ClientID != "",
+	}
+	for key, set := range fileValues {
+		if set {
+			resolved.sources[key] = sourceFile
+		}
+	}
+	for _, key := range []string{"user_id", "team_id", "user_handle", "user_email"} {
+		if fileValues[key] {
+			resolved.sources[key] = accountSource
+		}
+	}
+	if cfg.FigmaToken != "" {
+		resolved.sources["figma_token"] = storeSource
+	}
+	if cfg.OAuth != nil {
+		resolved.sources["oauth"] = storeSource
+	}
+
+	if token := os.Getenv(envFigmaToken); token != "" {
+		resolved.FigmaToken = token
+		resolved.sources["figma_token"] = "env " + envFigmaToken
+		// An explicit token is meant to be used, not the stored OAuth login
+		resolved.OAuth = nil
+		delete(resolved.sources, "oauth")
+	}
+	if teamID := os.Getenv(envFigmaTeamID); teamID != "" {
+		resolved.TeamID = teamID
+		resolved.sources["team_id"] = "env " + envFigmaTeamID
+	}
+	if userID := os.Getenv(envFigmaUserID); userID != "" {
+		if userID != cfg.UserID {
+			// The stored handle and email describe someone else
+			resolved.UserHandle = ""
+			resolved.UserEmail = ""
+			delete(resolved.sources, "user_handle")
+			delete(resolved.sources, "user_email")
+		}
+		resolved.UserID = userID
+		resolved.sources["user_id"] = "env " + envFigmaUserID
+	}
+
+	return resolved, contextErr
+}
+
+// fromEnv reports whether value is just what the environment variable supplies
+func fromEnv(name, value string) bool {
+	env := os.Getenv(name)
+	return env != "" && env == value
+}
+
+// runConfigShow prints config.json, or with --resolved every effective value and where it came from.
+// Flags given before the command (-u, -concurrency) are included, since they take precedence.
+func runConfigShow(args []string, opts cliOptions) error {
+	resolvedMode := false
+	for _, arg := range args {
+		switch arg {
+		case "--resolved", "-resolved":
+			resolvedMode = true
+		default:
+			return fmt.Errorf("usage: figma-beacon config show [--resolved]")
+		}
+	}
+
+	if !resolvedMode {
+		configPath, err := getConfigPath()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(configPath)
+		if os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "No config file at %s\n", configPath)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Println(strings.TrimSpace(string(data)))
+		return nil
+	}
+
+	dir, dirSource, err := configBaseDir()
+	if err != nil {
+		return err
+	}
+
+	resolved, err := resolveConfig(opts.Context)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if opts.Users != "" {
+		resolved.UserID = opts.Users
+		resolved.sources["user_id"] = "flag -u"
+	}
+	if opts.Concurrency > 0 {
+		resolved.Concurrency = opts.Concurrency
+		resolved.sources["concurrency"] = "flag -concurrency"
+	}
+
+	settings := NewFigmaClient("", resolved.apiSettings)
+	credentialSource := "none stored"
+	if _, source, err := loadCredentials(); err == nil && source != "" {
+		credentialSource = source
+	}
+	storePreference := resolved.CredentialStore
+	if storePreference == "" {
+		storePreference = credentialStoreAuto
+	}
+	concurrency := resolved.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	timezone := resolved.Timezone
+	if timezone == "" {
+		timezone = "machine's (" + zoneName(time.Local, time.Now()) + ")"
+	}
+	oauthValue := "not logged in"
+	if resolved.OAuth != nil {
+		oauthValue = "logged in"
+	}
+
+	rows := [][3]string{
+		{"config_dir", dir, dirSource},
+		{"current_context", resolved.context, resolved.source("current_context")},
+		{"figma_token", maskSecret(resolved.FigmaToken), resolved.source("figma_token")},
+		{"oauth", oauthValue, resolved.source("oauth")},
+		{"credential_store", storePreference + " (stored in: " + credentialSource + ")", resolved.source("credential_store")},
+		{"user_id", resolved.UserID, resolved.source("user_id")},
+		{"user_handle", resolved.UserHandle, resolved.source("user_handle")},
+		{"user_email", resolved.UserEmail, resolved.source("user_email")},
+		{"team_id", resolved.TeamID, resolved.source("team_id")},
+		{"concurrency", strconv.Itoa(concurrency), resolved.source("concurrency")},
+		{"timezone", timezone, resolved.source("timezone")},
+		{"api_base_url", settings.BaseURL, resolved.source("api_base_url")},
+		{"api_timeout_seconds", strconv.Itoa(int(settings.HTTPClient.Timeout.Seconds())), resolved.source("api_timeout_seconds")},
+		{"user_agent", settings.UserAgent, resolved.source("user_agent")},
+		{"max_retries", strconv.Itoa(settings.MaxRetries), resolved.source("max_retries")},
+		{"retry_budget", strconv.Itoa(settings.retryBudget), resolved.source("retry_budget")},
+		{"oauth_client_id", resolved.ClientID, resolved.source("oauth_client_id")},
+	}
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row[0]))
+	}
+	for _, row := range rows {
+		value := row[1]
+		if value == "" {
+			value = "(not set)"
+		}
+		fmt.Printf("%-*s  %s  [%s]\n", width, row[0], value, row[2])
+	}
+	return nil
+}
+
+// maskSecret shows just enough of a token to tell which one is in use
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", 8) + secret[len(secret)-4:]
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// File name rules narrow down which files of a profile's projects are scanned.
+// A rule is a glob, where * matches any run of characters and ? one character (case-insensitive, everything
+// else literal, so "[OLD]*" means names starting with "[OLD]"), or a regular expression written as /pattern/.
+type fileFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// newFileFilter compiles include and exclude rules. With no include rules every name is included.
+func newFileFilter(include, exclude []string) (fileFilter, error) {
+	var filter fileFilter
+	for _, rule := range include {
+		re, err := compileNameRule(rule)
+		if err != nil {
+			return filter, err
+		}
+		filter.include = append(filter.include, re)
+	}
+	for _, rule := range exclude {
+		re, err := compileNameRule(rule)
+		if err != nil {
+			return filter, err
+		}
+		filter.exclude = append(filter.exclude, re)
+	}
+	return filter, nil
+}
+
+// allows reports whether a file name passes the rules: it matches an include rule (if there are any) and no exclude rule
+func (f fileFilter) allows(name string) bool {
+	if len(f.include) > 0 {
+		included := false
+		for _, re := range f.include {
+			if re.MatchString(name) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, re := range f.exclude {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// compileNameRule turns a rule into a regular expression. Globs match whole names case-insensitively;
+// /regex/ rules are used as written, so they match anywhere in a name unless anchored.
+func compileNameRule(rule string) (*regexp.Regexp, error) {
+	rule = strings.TrimSpace(rule)
+	if rule == "" {
+		return nil, fmt.Errorf("empty file name rule")
+	}
+
+	if len(rule) >= 2 && strings.HasPrefix(rule, "/") && strings.HasSuffix(rule, "/") {
+		re, err := regexp.Compile(rule[1 : len(rule)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %s: %w", rule, err)
+		}
+		return re, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("(?i)^")
+	for _, r := range rule {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.MustCompile(sb.String()), nil
+}
+
+// ruleList collects a repeatable flag, e.g. -exclude 'Archive*' -exclude '[OLD]*'.
+// Rules aren't comma-separated because regular expressions may contain commas.
+type ruleList struct {
+	rules []string
+}
+
+func (l *ruleList) String() string {
+	return strings.Join(l.rules, " ")
+}
+
+func (l *ruleList) Set(value string) error {
+	if _, err := compileNameRule(value); err != nil {
+		return err
+	}
+	l.rules = append(l.rules, value)
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import "testing"
+
+func TestFileFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		allowed []string
+		denied  []string
+	}{
+		{
+			name:    "no rules",
+			allowed: []string{"Checkout", "Archive 2024"},
+		},
+		{
+			name:    "glob prefix",
+			exclude: []string{"Archive*"},
+			allowed: []string{"Checkout", "Old Archive"},
+			denied:  []string{"Archive", "Archive 2024"},
+		},
+		{
+			name:    "brackets are literal",
+			exclude: []string{"[OLD]*"},
+			allowed: []string{"OLD Checkout", "O Checkout", "Checkout [OLD]"},
+			denied:  []string{"[OLD] Checkout", "[old] Checkout"},
+		},
+		{
+			name:    "literal name",
+			exclude: []string{"Untitled"},
+			allowed: []string{"Untitled 2", "My Untitled"},
+			denied:  []string{"Untitled", "untitled", "UNTITLED"},
+		},
+		{
+			name:    "single character wildcard",
+			include: []string{"v? Checkout"},
+			allowed: []string{"v2 Checkout"},
+			denied:  []string{"v Checkout", "v10 Checkout"},
+		},
+		{
+			name:    "unanchored regular expression",
+			exclude: []string{"/draft/"},
+			allowed: []string{"Draft review"},
+			denied:  []string{"Checkout draft", "drafts"},
+		},
+		{
+			name:    "anchored regular expression",
+			include: []string{"/^(Web|iOS) /"},
+			allowed: []string{"Web Checkout", "iOS Settings"},
+			denied:  []string{"Android Checkout", "New Web Checkout", "web Checkout"},
+		},
+		{
+			name:    "include combined with exclude",
+			include: []string{"Web*", "iOS*"},
+			exclude: []string{"*Archive*", "Untitled"},
+			allowed: []string{"Web Checkout", "ios Settings"},
+			denied:  []string{"Web Archive", "iOS old archive", "Android Checkout", "Untitled"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := newFileFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.allowed {
+				if !filter.allows(name) {
+					t.Errorf("%q was filtered out", name)
+				}
+			}
+			for _, name := range tt.denied {
+				if filter.allows(name) {
+					t.Errorf("%q was let through", name)
+				}
+			}
+		})
+	}
+}
+
+func TestFileFilterInvalidRules(t *testing.T) {
+	for _, rule := range []string{" ", "/[unclosed/"} {
+		if _, err := newFileFilter(nil, []string{rule}); err == nil {
+			t.Errorf("rule %q was accepted", rule)
+		}
+	}
+}
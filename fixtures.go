@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// fixtureOptions selects recording (-record) or replaying (-replay) of Figma API traffic.
+// Fixtures are keyed by method, path and query, so a replay works whatever base URL or token recorded it.
+type fixtureOptions struct {
+	RecordDir string
+	ReplayDir string
+}
+
+func (f fixtureOptions) active() bool {
+	return f.RecordDir != "" || f.ReplayDir != ""
+}
+
+// prepare checks the flags and creates the record directory before anything runs
+func (f fixtureOptions) prepare() error {
+	if f.RecordDir != "" && f.ReplayDir != "" {
+		return fmt.Errorf("-record and -replay can't be used together")
+	}
+	if f.RecordDir != "" {
+		if err := os.MkdirAll(f.RecordDir, 0755); err != nil {
+			return fmt.Errorf("failed to create record directory: %w", err)
+		}
+	}
+	if f.ReplayDir != "" {
+		if info, err := os.Stat(f.ReplayDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("replay directory '%s' not found", f.ReplayDir)
+		}
+	}
+	return nil
+}
+
+// apply routes the client's requests through the recorder or the replayer.
+// The response cache is turned off so every request is recorded, and replays never touch it.
+func (f fixtureOptions) apply(client *FigmaClient) {
+	switch {
+	case f.RecordDir != "":
+		client.HTTPClient.Transport = &recordingTransport{dir: f.RecordDir, next: http.DefaultTransport}
+		client.Cache = nil
+	case f.ReplayDir != "":
+		client.HTTPClient.Transport = &replayTransport{dir: f.ReplayDir}
+		client.Cache = nil
+		// Recordings don't contain the token, so none is needed to replay them
+		client.Auth = nil
+		if client.Token == "" {
+			client.Token = "replay"
+		}
+	}
+}
+
+// fixtureRun is saved as run.json next to the recorded requests. A replay reuses its window: resolving
+// "-t week" again on a later day would ask for files and versions the recording never fetched.
+type fixtureRun struct {
+	Window      TimeWindow `json:"window"`
+	Location    string     `json:"location"` // zone the window was counted in; "Local" for the recording machine's
+	GeneratedAt time.Time  `json:"generated_at"`
+}
+
+const fixtureRunFile = "run.json"
+
+// recordRun saves the window and generation time of a recorded report
+func (f fixtureOptions) recordRun(report *ActivityReport) error {
+	if f.RecordDir == "" {
+		return nil
+	}
+	run := fixtureRun{
+		Window:      report.TimeWindow,
+		Location:    report.TimeWindow.Start.Location().String(),
+		GeneratedAt: report.GeneratedAt,
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(f.RecordDir, fixtureRunFile), data, 0644); err != nil {
+		return fmt.Errorf("failed to record the report window: %w", err)
+	}
+	return nil
+}
+
+// recordedRun loads the run being replayed. It returns nil when not replaying,
+// or for recordings made before run.json existed, whose window is resolved again.
+func (f fixtureOptions) recordedRun() (*fixtureRun, error) {
+	if f.ReplayDir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(f.ReplayDir, fixtureRunFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var run fixtureRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("invalid %s in replay directory: %w", fixtureRunFile, err)
+	}
+	// JSON keeps only the UTC offset; restore the zone so days across a DST change still line up
+	if run.Location != "" && run.Location != "Local" {
+		if location, err := time.LoadLocation(run.Location); err == nil {
+			run.Window.Start = run.Window.Start.In(location)
+			run.Window.End = run.Window.End.In(location)
+		}
+	}
+	return &run, nil
+}
+
+// fixture is one recorded exchange, stored as JSON so it can be read and edited by hand
+type fixture struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"` // path and query only
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// Response headers worth keeping; everything else is noise in a fixture
+var fixtureHeaders = []string{"Content-Type", "Retry-After"}
+
+var fixtureNameChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// fixturePath names the file for a request: a readable prefix plus a hash of the exact path and query
+func fixturePath(dir string, req *http.Request) string {
+	target := req.URL.EscapedPath()
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.Query().Encode() // Encode sorts the keys
+	}
+
+	sum := sha256.Sum256([]byte(req.Method + " " + target))
+	readable := strings.Trim(fixtureNameChars.ReplaceAllString(req.Method+"_"+req.URL.Path, "_"), "_")
+	if len(readable) > 80 {
+		readable = readable[:80]
+	}
+
+	return filepath.Join(dir, readable+"-"+hex.EncodeToString(sum[:4])+".json")
+}
+
+type recordingTransport struct {
+	dir  string
+	next http.RoundTripper
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	target := req.URL.EscapedPath()
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+
+	recorded := fixture{
+		Method:  req.Method,
+		URL:     target,
+		Status:  resp.StatusCode,
+		Headers: make(map[string]string),
+		Body:    string(body),
+	}
+	for _, name := range fixtureHeaders {
+		if value := resp.Header.Get(name); value != "" {
+			recorded.Headers[name] = value
+		}
+	}
+
+	// Retried requests overwrite earlier attempts, so the fixture holds the final answer
+	data, err := json.MarshalIndent(recorded, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(fixturePath(t.dir, req), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to record response: %w", err)
+	}
+
+	return resp, nil
+}
+
+type replayTransport struct {
+	dir string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(fixturePath(t.dir, req))
+	if err != nil {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL.RequestURI())
+	}
+
+	var recorded fixture
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("invalid fixture for %s %s: %w", req.Method, req.URL.RequestURI(), err)
+	}
+
+	header := make(http.Header)
+	for name, value := range recorded.Headers {
+		header.Set(name, value)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
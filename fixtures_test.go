@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// standInFigma serves a small project: a file edited in the window with a branch, and a file
+// left alone except for a comment. It counts the requests it answers.
+func standInFigma(t *testing.T, requests *atomic.Int64) *httptest.Server {
+	t.Helper()
+	responses := map[string]string{
+		"/v1/projects/p1/files": `{"files": [
+			{"key": "a", "name": "Checkout", "last_modified": "2026-10-09T10:00:00Z",
+			 "branches": [{"key": "ab", "name": "Dark mode", "last_modified": "2026-10-10T09:00:00Z"}]},
+			{"key": "b", "name": "Settings", "last_modified": "2026-09-20T12:00:00Z"}]}`,
+		"/v1/files/a": `{"name": "Checkout", "lastModified": "2026-10-09T10:00:00Z", "version": "3"}`,
+		"/v1/files/a/versions": `{"versions": [
+			{"id": "3", "created_at": "2026-10-09T10:00:00Z", "label": "Ready for review", "user": {"id": "u1", "handle": "alice"}},
+			{"id": "2", "created_at": "2026-10-07T08:00:00Z", "user": {"id": "u2", "handle": "bob"}},
+			{"id": "1", "created_at": "2026-09-01T08:00:00Z", "user": {"id": "u1", "handle": "alice"}}], "pagination": {}}`,
+		"/v1/files/a/comments": `{"comments": []}`,
+		"/v1/files/ab":         `{"name": "Checkout", "lastModified": "2026-10-10T09:00:00Z", "version": "5"}`,
+		"/v1/files/ab/versions": `{"versions": [
+			{"id": "5", "created_at": "2026-10-10T09:00:00Z", "user": {"id": "u1", "handle": "alice"}},
+			{"id": "4", "created_at": "2026-10-08T17:00:00Z", "user": {"id": "u1", "handle": "alice"}}], "pagination": {}}`,
+		"/v1/files/ab/comments": `{"comments": []}`,
+		"/v1/files/b/comments": `{"comments": [
+			{"id": "c1", "message": "@alice spacing looks off", "created_at": "2026-10-08T11:00:00Z", "user": {"id": "u2", "handle": "bob"}},
+			{"id": "c0", "message": "First pass", "created_at": "2026-09-21T11:00:00Z", "user": {"id": "u2", "handle": "bob"}}]}`,
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"status": 404, "err": "Not found"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	var requests atomic.Int64
+	server := standInFigma(t, &requests)
+	dir := t.TempDir()
+
+	profile := &Profile{Name: "web", SelectedProjects: []ProfileProject{{ID: "p1", Name: "Web"}}}
+	window := TimeWindow{Start: day(2026, 10, 5), End: day(2026, 10, 12), Zone: testZone.String()}
+	opts := scanOptions{Concurrency: 2, UserIDs: []string{"u1"}, IncludeTeammates: true, UserHandle: "alice"}
+
+	// Record against the stand-in server
+	recorder := fixtureOptions{RecordDir: dir}
+	if err := recorder.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	client := NewFigmaClient("figd_test", apiSettings{BaseURL: server.URL})
+	recorder.apply(client)
+	recorded := scanActivity(context.Background(), client, profile, window, "u1", "alice", opts)
+	if err := recorder.recordRun(recorded); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(recorded.Warnings) > 0 {
+		t.Fatalf("recording had warnings: %+v", recorded.Warnings)
+	}
+	if len(recorded.Files) != 2 || len(recorded.Files[0].Branches) != 1 || recorded.TotalComments != 1 {
+		t.Fatalf("recorded %+v, want Checkout with its branch and Settings with a comment", recorded.Files)
+	}
+
+	// Replay offline, with no token and nothing listening at the base URL
+	server.Close()
+	recordedRequests := requests.Load()
+	replayer := fixtureOptions{ReplayDir: dir}
+	if err := replayer.prepare(); err != nil {
+		t.Fatal(err)
+	}
+	run, err := replayer.recordedRun()
+	if err != nil || run == nil {
+		t.Fatalf("recorded run = %v, %v", run, err)
+	}
+	if !run.Window.Start.Equal(window.Start) || !run.Window.End.Equal(window.End) || run.Window.Start.Location().String() != testZone.String() {
+		t.Errorf("recorded window = %v to %v, want %v to %v", run.Window.Start, run.Window.End, window.Start, window.End)
+	}
+
+	client = NewFigmaClient("", apiSettings{BaseURL: "http://replay.invalid"})
+	replayer.apply(client)
+	replayed := scanActivity(context.Background(), client, profile, run.Window, "u1", "alice", opts)
+	replayed.GeneratedAt = run.GeneratedAt
+
+	if sent := requests.Load() - recordedRequests; sent > 0 {
+		t.Errorf("replay sent %d requests to the server", sent)
+	}
+	if len(replayed.Warnings) > 0 {
+		t.Fatalf("replay had warnings: %+v", replayed.Warnings)
+	}
+	if got, want := formatReportMarkdown(replayed), formatReportMarkdown(recorded); got != want {
+		t.Errorf("replayed report differs\n--- replayed\n%s\n--- recorded\n%s", got, want)
+	}
+	got, _ := json.Marshal(replayed)
+	want, _ := json.Marshal(recorded)
+	if string(got) != string(want) {
+		t.Errorf("replayed JSON differs\n--- replayed\n%s\n--- recorded\n%s", got, want)
+	}
+}
+
+func TestReplayMissingFixture(t *testing.T) {
+	replayer := fixtureOptions{ReplayDir: t.TempDir()}
+	client := NewFigmaClient("", apiSettings{})
+	replayer.apply(client)
+
+	if _, err := client.ProjectFiles(context.Background(), "p1"); err == nil {
+		t.Errorf("replay without a recording succeeded")
+	}
+}
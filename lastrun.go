@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// "Since last run" reports start where the profile's previous successful since-last report ended,
+// so a daily job neither misses nor repeats activity, even when a run is skipped.
+// The end of that window is kept next to the profile, in <name>.lastrun.
+type lastRun struct {
+	End time.Time `json:"end"` // End of the last reported window; the next one starts here
+}
+
+// Window used when a profile has no previous run
+const firstRunDays = 7
+
+func lastRunPath(profileName string) (string, error) {
+	profilesDir, err := getProfilesPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(profilesDir, profileName+".lastrun"), nil
+}
+
+// loadLastRun returns the end of the profile's last since-last window, or zero if it never ran
+func loadLastRun(profileName string) (time.Time, error) {
+	path, err := lastRunPath(profileName)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var run lastRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return time.Time{}, err
+	}
+	return run.End, nil
+}
+
+func saveLastRun(profileName string, end time.Time) error {
+	path, err := lastRunPath(profileName)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(lastRun{End: end}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// advanceLastRun moves the profile's marker to the end of a since-last report.
+// Cancelled or incomplete reports leave it alone so the next run covers their window again.
+func advanceLastRun(profileName string, config ReportConfig, report *ActivityReport) error {
+	if config.TimeMode != timeModeSinceLast || report.Cancelled || len(report.Warnings) > 0 {
+		return nil
+	}
+	return saveLastRun(profileName, report.TimeWindow.End)
+}
+
+// moveLastRun keeps the marker with a renamed profile
+func moveLastRun(oldName, newName string) error {
+	oldPath, err := lastRunPath(oldName)
+	if err != nil {
+		return err
+	}
+	newPath, err := lastRunPath(newName)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(oldPath, newPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func deleteLastRun(profileName string) error {
+	path, err := lastRunPath(profileName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
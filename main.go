@@ -1,11 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 	"sort"
@@ -36,6 +36,8 @@ type wizardStep int
 const (
 	wizardTeamID wizardStep = iota
 	wizardProjects
+	wizardFiles
+	wizardFilters
 	wizardSaveName
 )
 
@@ -44,31 +46,52 @@ type loadingState int
 const (
 	notLoading loadingState = iota
 	loadingProjects
+	loadingFile
 )
 
 // Profile data structures
 type ProfileProject struct {
-	ID   string `json:"id"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	TeamID   string `json:"team_id,omitempty"` // Profiles saved before multi-team support leave this empty; loadProfile fills it in
+	TeamName string `json:"team_name,omitempty"`
+}
+
+// ProfileFile is a file the profile watches on its own, whichever project it is in
+type ProfileFile struct {
+	Key  string `json:"key"`
 	Name string `json:"name"`
 }
 
 type Profile struct {
 	Name             string           `json:"name"`
-	TeamID           string           `json:"team_id"`
+	TeamID           string           `json:"team_id"` // First team; each project records the team it belongs to
 	SelectedProjects []ProfileProject `json:"selected_projects"`
+	Files            []ProfileFile    `json:"files,omitempty"`   // Watched files, scanned without listing their projects
+	Include          []string         `json:"include,omitempty"` // File name rules (glob or /regex/); only matching files of the projects are scanned
+	Exclude          []string         `json:"exclude,omitempty"` // File name rules for files of the projects to leave out
 	CreatedAt        time.Time        `json:"created_at"`
 	IsDefault        bool             `json:"is_default"`
+	Concurrency      int              `json:"concurrency,omitempty"` // Parallel file fetches, overrides config
+	Context          string           `json:"context,omitempty"`     // Context the profile belongs to; empty is the default context
+	Sprint           *Sprint          `json:"sprint,omitempty"`      // Sprint cadence for -t sprint, edited in the .beacon file
+	Timezone         string           `json:"timezone,omitempty"`    // Zone report windows are counted in, overrides config
 }
 
 type FigmaProject struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	TeamID   string // Not from API, added by us
+	TeamName string // Not from API, added by us
 }
 
 type FigmaFile struct {
-	Key       string `json:"key"`
-	Name      string `json:"name"`
-	ProjectID string // Not from API, added by us
+	Key          string        `json:"key"`
+	Name         string        `json:"name"`
+	ThumbnailURL string        `json:"thumbnail_url"`
+	LastModified time.Time     `json:"last_modified"`
+	Branches     []FigmaBranch `json:"branches"`
+	ProjectID    string        // Not from API, added by us
 }
 
 // Report generator data structures
@@ -80,17 +103,60 @@ const (
 	timeModeThisMonthToDate timeMode = "this_month_to_date"
 	timeModeLast4Weeks      timeMode = "last_4_weeks"
 	timeModeLast30Days      timeMode = "last_30_days"
+	timeModePrevWeek        timeMode = "previous_week" // Monday to Sunday
+	timeModeThisWeek        timeMode = "this_week"
+	timeModePrevQuarter     timeMode = "previous_quarter"
+	timeModeThisQuarter     timeMode = "this_quarter"
+	timeModeYearToDate      timeMode = "year_to_date"
+	timeModePrevYear        timeMode = "previous_year"
+	timeModeSprint          timeMode = "sprint"
+	timeModeSinceLast       timeMode = "since_last_run"
+	timeModeCustom          timeMode = "custom"
 )
 
+// Time windows offered on the report config screen, in the order of reportTimeOptions
+var reportTimeChoices = []ReportConfig{
+	{TimeMode: timeModeLastWeek},
+	{TimeMode: timeModeLastMonth},
+	{TimeMode: timeModeThisMonthToDate},
+	{TimeMode: timeModeLast4Weeks},
+	{TimeMode: timeModeLast30Days},
+	{TimeMode: timeModePrevWeek},
+	{TimeMode: timeModeThisWeek},
+	{TimeMode: timeModePrevQuarter},
+	{TimeMode: timeModeThisQuarter},
+	{TimeMode: timeModeYearToDate},
+	{TimeMode: timeModeSprint},
+	{TimeMode: timeModeSprint, SprintsBack: 1},
+	{TimeMode: timeModeSinceLast},
+	{TimeMode: timeModeCustom},
+}
+
 type ReportConfig struct {
-	TimeMode  timeMode
-	FileKeys  []string
-	ProjectID string
+	TimeMode    timeMode
+	From        time.Time // Window of timeModeCustom; for timeModeSinceLast, the end of the last run (zero if none)
+	To          time.Time
+	Sprint      *Sprint        // Cadence of timeModeSprint, from the profile
+	SprintsBack int            // 0 for the current sprint, 1 for the previous one
+	Location    *time.Location // Zone days are counted in; nil for the machine's
+	FileKeys    []string       // Files to scan directly in addition to the profile's, e.g. from -files
+	ProjectID   string
 }
 
+// TimeWindow is half-open: it includes Start and runs up to, but not including, End
 type TimeWindow struct {
 	Start time.Time
 	End   time.Time
+	Zone  string // time zone the window's days are counted in
+}
+
+func (w TimeWindow) contains(t time.Time) bool {
+	return !t.Before(w.Start) && t.Before(w.End)
+}
+
+// lastDay is the date of the window's final moment, for display
+func (w TimeWindow) lastDay() string {
+	return w.End.Add(-time.Nanosecond).Format("2006-01-02")
 }
 
 type FigmaFileMetadata struct {
@@ -98,6 +164,7 @@ type FigmaFileMetadata struct {
 	Name         string    `json:"name"`
 	LastModified time.Time `json:"lastModified"`
 	ThumbnailURL string    `json:"thumbnailUrl"`
+	Version      string    `json:"version"`
 }
 
 type FigmaVersion struct {
@@ -122,25 +189,52 @@ type FigmaComment struct {
 }
 
 type FileActivity struct {
-	FileKey         string
-	FileName        string
-	ProjectName     string
-	Versions        []FigmaVersion
-	Comments        []FigmaComment
-	LastModified    time.Time
-	CreatedAt       time.Time
-	MyChanges       bool // indicates if the user made changes in the time window
-	CreatedInWindow bool // indicates if file was created in the time window
+	FileKey             string
+	FileName            string
+	ProjectName         string
+	TeamID              string `json:",omitempty"`
+	TeamName            string `json:",omitempty"`
+	Versions            []FigmaVersion
+	Comments            []FigmaComment
+	LastModified        time.Time
+	CreatedAt           time.Time      // zero when the file predates the window and its first version wasn't fetched
+	MyChanges           bool           // indicates if the user saved a version in the time window
+	CreatedInWindow     bool           // indicates if file was created in the time window
+	TeammateChanges     bool           // indicates if someone other than the user changed the file in the time window
+	UnattributedChanges bool           // edited in the window without a saved version yet, so the author is unknown
+	Teammates           []string       `json:",omitempty"` // handles of the other users who saved versions
+	MainFileKey         string         `json:",omitempty"` // set on branches: the file they branch from
+	MainFileName        string         `json:",omitempty"`
+	BranchStatus        string         `json:",omitempty"` // "archived" or "merged" for branches no longer listed
+	Branches            []FileActivity `json:",omitempty"` // branch activity, nested under the main file
+}
+
+// ReportWarning records a project or file the scan could not read.
+// Without these a report with missing projects would look the same as a quiet week.
+type ReportWarning struct {
+	Scope       string // "project" or "file"
+	ProjectName string
+	FileKey     string `json:",omitempty"`
+	FileName    string `json:",omitempty"`
+	Message     string
 }
 
 type ActivityReport struct {
-	TimeWindow   TimeWindow
-	UserID       string
-	UserHandle   string
-	Files        []FileActivity
-	TotalFiles   int
-	TotalChanges int
-	GeneratedAt  time.Time
+	TimeWindow    TimeWindow
+	UserID        string
+	UserHandle    string
+	Files         []FileActivity
+	Warnings      []ReportWarning
+	TotalFiles    int
+	TotalChanges  int
+	GeneratedAt   time.Time
+	Teammates     bool // files changed only by teammates are included
+	TotalComments int
+	MyComments    bool             // only comments by or mentioning the user are included
+	Cancelled     bool             // the scan was stopped before it finished
+	Unscanned     int              // listed files the cancelled scan never reached
+	FilteredOut   int              // listed files left out by the include/exclude rules
+	Timeline      []TimelineBucket // versions and comments per day, or per week for long windows
 }
 
 type model struct {
@@ -169,6 +263,9 @@ type model struct {
 	wizardTeamID       string
 	wizardProjects     []FigmaProject
 	wizardSelectedProj map[string]bool
+	wizardFiles        []ProfileFile
+	wizardInclude      []string
+	wizardExclude      []string
 	wizardProfileName  string
 	wizardEditMode     bool // true if editing existing profile, false if creating new
 	loadingState       loadingState
@@ -180,19 +277,38 @@ type model struct {
 	showDeleteConfirm bool
 	deleteProfileName string
 	// Report generator fields
-	reportConfig      ReportConfig
-	reportTimeOptions []string
-	reportTimeIndex   int
-	reportProfileIndex int // Selected profile index for report
-	generatingReport  bool
-	reportingProfile  *Profile // Profile being used for current report generation
-	activityReport    *ActivityReport
-	reportError       string
-	reportContent     string
-	exportSuccess     string
-	exportError       string
-	spinnerFrame      int    // Current spinner frame
-	spinnerChars      []string // Spinner characters
+	reportConfig       ReportConfig
+	reportTimeOptions  []string
+	reportTimeIndex    int
+	reportCustomFrom   string // Custom range form; dates or expressions like last-monday
+	reportCustomTo     string
+	reportCustomField  int    // Field being edited in the custom range form: 0 from, 1 to, -1 none
+	reportTimeError    string // Custom range or sprint that can't be used
+	reportLastRun      string // Where a since-last report for the selected profile would start, while that option is selected
+	reportProfileIndex int    // Selected profile index for report
+	reportTeammates    bool   // Include files changed only by teammates
+	reportMyComments   bool   // Only comments by or mentioning the user
+	generatingReport   bool
+	reportingProfile   *Profile // Profile being used for current report generation
+	activityReport     *ActivityReport
+	reportError        string
+	reportContent      string
+	exportSuccess      string
+	exportError        string
+	spinnerFrame       int                // Current spinner frame
+	spinnerChars       []string           // Spinner characters
+	api                apiSettings        // API base URL, timeout and user agent from config
+	oauthLoggedIn      bool               // Logged in with `figma-beacon login`; no personal access token needed
+	context            string             // Active context: the Figma account in use
+	contexts           []string           // Every context, for the Setup screen's switcher
+	concurrency        int                // Parallel file fetches from config
+	timezone           string             // Zone report windows are counted in, from config; profiles may override it
+	noCache            bool               // Bypass the on-disk response cache (-no-cache)
+	fixtures           fixtureOptions     // Record or replay API traffic (-record, -replay)
+	reportRun          int                // Identifies the current report run so messages from an abandoned one are dropped
+	cancelReport       context.CancelFunc // Stops the running scan
+	cancellingReport   bool
+	reportProgress     scanProgress
 }
 
 type userInfoMsg struct {
@@ -209,12 +325,21 @@ type userInfoErrMsg struct {
 type projectsCompleteMsg struct {
 	projects []FigmaProject
 	count    int
+	teams    int
 }
 
 type projectsErrMsg struct {
 	err string
 }
 
+type fileLookupMsg struct {
+	file ProfileFile
+}
+
+type fileLookupErrMsg struct {
+	err string
+}
+
 type profileSavedMsg struct {
 	profileName string
 }
@@ -225,14 +350,22 @@ type profilesLoadedMsg struct {
 
 // Report generator message types
 type reportGeneratedMsg struct {
+	run     int
 	report  *ActivityReport
 	content string
 }
 
 type reportErrMsg struct {
+	run int
 	err string
 }
 
+type reportProgressMsg struct {
+	run      int
+	progress scanProgress
+	updates  <-chan scanProgress
+}
+
 type reportExportedMsg struct {
 	filepath string
 }
@@ -244,11 +377,19 @@ type reportExportErrMsg struct {
 type tickMsg time.Time
 
 type config struct {
-	FigmaToken string `json:"figma_token"`
-	UserID     string `json:"user_id"`
-	TeamID     string `json:"team_id"`
-	UserHandle string `json:"user_handle"`
-	UserEmail  string `json:"user_email"`
+	FigmaToken      string                     `json:"figma_token,omitempty"` // Kept in the credential store, never written to config.json
+	UserID          string                     `json:"user_id"`
+	TeamID          string                     `json:"team_id"`
+	UserHandle      string                     `json:"user_handle"`
+	UserEmail       string                     `json:"user_email"`
+	Concurrency     int                        `json:"concurrency,omitempty"`      // Parallel file fetches during a scan
+	OAuth           *oauthToken                `json:"oauth,omitempty"`            // Set by `figma-beacon login`; kept in the credential store
+	CredentialStore string                     `json:"credential_store,omitempty"` // auto (default), keyring, encrypted or file
+	CurrentContext  string                     `json:"current_context,omitempty"`  // Set by `figma-beacon context use`; empty means default
+	Contexts        map[string]contextSettings `json:"contexts,omitempty"`         // Extra accounts; the fields above are the default context
+	Timezone        string                     `json:"timezone,omitempty"`         // IANA zone report windows are counted in, e.g. Europe/Madrid; empty for the machine's
+	apiSettings
+	oauthSettings
 }
 
 type setupItem struct {
@@ -262,17 +403,25 @@ type menuItem struct {
 	warning     string
 }
 
-func getConfigPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+func getConfigDir() (string, error) {
+	configDir, _, err := configBaseDir()
 	if err != nil {
 		return "", err
 	}
 
-	configDir := filepath.Join(homeDir, ".config", "figma-beacon")
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return "", err
 	}
 
+	return configDir, nil
+}
+
+func getConfigPath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+
 	return filepath.Join(configDir, "config.json"), nil
 }
 
@@ -282,6 +431,12 @@ func saveConfig(cfg config) error {
 		return err
 	}
 
+	// Secrets go to the credential store; config.json only keeps settings
+	if err := saveCredentials(cfg.CredentialStore, cfg.credentials()); err != nil {
+		return err
+	}
+	cfg.setCredentials(credentials{})
+
 	data, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
 		return err
@@ -299,29 +454,105 @@ func loadConfig() (config, error) {
 	}
 
 	data, err := os.ReadFile(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Config file doesn't exist yet, return empty config
-			return cfg, nil
-		}
+	if err != nil && !os.IsNotExist(err) {
 		return cfg, err
 	}
+	if err == nil {
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return cfg, err
+		}
+	}
 
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	// Older versions saved secrets in config.json; anything found there is newer than the store
+	inline := cfg.credentials()
+
+	stored, source, err := loadCredentials()
+	if err != nil {
+		cfg.setCredentials(credentials{})
 		return cfg, err
 	}
 
+	if inline.empty() {
+		cfg.setCredentials(stored)
+		// Move to a better backend once one is available (e.g. after setting a passphrase) or to the one asked for
+		if target, err := credentialTarget(cfg.CredentialStore); err == nil && source != "" && source != target.name() {
+			saveCredentials(cfg.CredentialStore, stored)
+		}
+		return cfg, nil
+	}
+
+	// Migrate: move the inline secrets to the store and rewrite config.json without them
+	if inline.FigmaToken == "" {
+		inline.FigmaToken = stored.FigmaToken
+	}
+	if inline.OAuth == nil {
+		inline.OAuth = stored.OAuth
+	}
+	if inline.OAuthClientSecret == "" {
+		inline.OAuthClientSecret = stored.OAuthClientSecret
+	}
+	for name, secrets := range stored.Contexts {
+		if _, ok := inline.Contexts[name]; !ok {
+			if inline.Contexts == nil {
+				inline.Contexts = make(map[string]contextCredentials)
+			}
+			inline.Contexts[name] = secrets
+		}
+	}
+	cfg.setCredentials(inline)
+	if err := saveConfig(cfg); err != nil {
+		return cfg, fmt.Errorf("failed to move credentials out of config.json: %w", err)
+	}
+
 	return cfg, nil
 }
 
+// credentials returns the secrets held in cfg, including those of every named context
+func (cfg config) credentials() credentials {
+	creds := credentials{
+		FigmaToken:        cfg.FigmaToken,
+		OAuth:             cfg.OAuth,
+		OAuthClientSecret: cfg.ClientSecret,
+	}
+	for name, ctx := range cfg.Contexts {
+		if ctx.FigmaToken == "" && ctx.OAuth == nil {
+			continue
+		}
+		if creds.Contexts == nil {
+			creds.Contexts = make(map[string]contextCredentials)
+		}
+		creds.Contexts[name] = contextCredentials{FigmaToken: ctx.FigmaToken, OAuth: ctx.OAuth}
+	}
+	return creds
+}
+
+// setCredentials puts creds into cfg; secrets of contexts no longer in cfg are dropped
+func (cfg *config) setCredentials(creds credentials) {
+	cfg.FigmaToken = creds.FigmaToken
+	cfg.OAuth = creds.OAuth
+	cfg.ClientSecret = creds.OAuthClientSecret
+
+	// Copy the map: cfg may share it with the caller's config
+	if cfg.Contexts != nil {
+		contexts := make(map[string]contextSettings, len(cfg.Contexts))
+		for name, ctx := range cfg.Contexts {
+			secrets := creds.Contexts[name]
+			ctx.FigmaToken = secrets.FigmaToken
+			ctx.OAuth = secrets.OAuth
+			contexts[name] = ctx
+		}
+		cfg.Contexts = contexts
+	}
+}
+
 // Profile storage functions
 func getProfilesPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := getConfigDir()
 	if err != nil {
 		return "", err
 	}
 
-	profilesDir := filepath.Join(homeDir, ".config", "figma-beacon", "profiles")
+	profilesDir := filepath.Join(configDir, "profiles")
 	if err := os.MkdirAll(profilesDir, 0755); err != nil {
 		return "", err
 	}
@@ -335,6 +566,11 @@ func saveProfile(profile Profile) error {
 		return err
 	}
 
+	// Profile files are named after the profile, so names are shared by all contexts
+	if existing, err := loadProfile(profile.Name); err == nil && normalizeContext(existing.Context) != normalizeContext(profile.Context) {
+		return fmt.Errorf("profile name '%s' is already used in context '%s'", profile.Name, normalizeContext(existing.Context))
+	}
+
 	fileName := profile.Name + ".beacon"
 	filePath := filepath.Join(profilesDir, fileName)
 
@@ -366,9 +602,50 @@ func loadProfile(name string) (Profile, error) {
 		return profile, err
 	}
 
+	// Single-team profiles only stored the team on the profile
+	for i := range profile.SelectedProjects {
+		if profile.SelectedProjects[i].TeamID == "" {
+			profile.SelectedProjects[i].TeamID = profile.TeamID
+		}
+	}
+
 	return profile, nil
 }
 
+// teamIDs lists the profile's teams in the order their projects were selected
+func (p Profile) teamIDs() []string {
+	var teams []string
+	seen := make(map[string]bool)
+	for _, project := range p.SelectedProjects {
+		teamID := project.TeamID
+		if teamID == "" {
+			teamID = p.TeamID
+		}
+		if teamID != "" && !seen[teamID] {
+			seen[teamID] = true
+			teams = append(teams, teamID)
+		}
+	}
+	if len(teams) == 0 && p.TeamID != "" {
+		teams = append(teams, p.TeamID)
+	}
+	return teams
+}
+
+// teamLabel names a team for display, falling back to its ID
+func teamLabel(teamID, teamName string) string {
+	switch {
+	case teamName != "" && teamID != "":
+		return fmt.Sprintf("%s (%s)", teamName, teamID)
+	case teamName != "":
+		return teamName
+	case teamID != "":
+		return "Team " + teamID
+	}
+	// Watched files aren't tied to a team
+	return "Other"
+}
+
 func loadAllProfiles() ([]Profile, error) {
 	profilesDir, err := getProfilesPath()
 	if err != nil {
@@ -401,8 +678,9 @@ func loadAllProfiles() ([]Profile, error) {
 	return profiles, nil
 }
 
-func setDefaultProfile(name string) error {
-	profiles, err := loadAllProfiles()
+// setDefaultProfile makes name the default profile of its context
+func setDefaultProfile(name, contextName string) error {
+	profiles, err := loadContextProfiles(contextName)
 	if err != nil {
 		return err
 	}
@@ -498,79 +776,215 @@ func buildMenuItems(profiles []Profile) []menuItem {
 	return menuItems
 }
 
-func initialModel() model {
+func initialModel(contextFlag string) model {
 	ti := textinput.New()
 	ti.Placeholder = ""
 	ti.CharLimit = 256
 	ti.Width = 80
 	ti.Prompt = ""
 
-	// Load saved configuration
-	cfg, _ := loadConfig()
+	// Load saved configuration, with environment overrides
+	cfg, cfgErr := resolveConfig(contextFlag)
+	// Unreadable credentials (e.g. a missing passphrase) are shown on the Setup screen
+	configError := ""
+	if cfgErr != nil {
+		configError = cfgErr.Error()
+	}
 
-	// Load profiles
-	profiles, _ := loadAllProfiles()
+	m := model{
+		selectedIndex:      1,
+		currentScreen:      mainMenuScreen,
+		setupIndex:         0,
+		textInput:          ti,
+		editingIndex:       -1,
+		fetchingUser:       false,
+		userFetchError:     configError,
+		wizardStep:         wizardTeamID,
+		wizardSelectedProj: make(map[string]bool),
+		loadingState:       notLoading,
+		listCursor:         0,
+		listOffset:         0,
+		showDeleteConfirm:  false,
+		deleteProfileName:  "",
+		reportTimeOptions:  []string{"Last Week", "Last Month", "This Month to Date", "Last 4 Weeks", "Last 30 Days", "Previous Week (Mon-Sun)", "This Week", "Previous Quarter", "This Quarter", "Year to Date", "Current Sprint", "Previous Sprint", "Since Last Run", "Custom Range"},
+		reportTimeIndex:    0,
+		reportCustomField:  -1,
+		reportProfileIndex: 0,
+		generatingReport:   false,
+		reportError:        "",
+		spinnerFrame:       0,
+		spinnerChars:       []string{"⬖", "⬗", "⬘", "⬙"},
+		api:                cfg.apiSettings,
+		concurrency:        cfg.Concurrency,
+		timezone:           cfg.Timezone,
+	}
+	m.useContext(cfg)
+	return m
+}
 
-	// Find default profile
-	var activeProfile *Profile
-	for i := range profiles {
-		if profiles[i].IsDefault {
-			activeProfile = &profiles[i]
+// useContext loads the account and profiles of cfg's context into the model
+func (m *model) useContext(cfg resolvedConfig) {
+	m.context = cfg.context
+	m.contexts = cfg.contextNames()
+	m.figmaToken = cfg.FigmaToken
+	m.userID = cfg.UserID
+	m.teamID = cfg.TeamID
+	m.userHandle = cfg.UserHandle
+	m.userEmail = cfg.UserEmail
+	m.oauthLoggedIn = cfg.OAuth != nil
+
+	// Load the context's profiles and find its default one
+	m.profiles, _ = loadContextProfiles(m.context)
+	m.activeProfile = nil
+	m.profileStatus = "⬥ No profile selected"
+	for i := range m.profiles {
+		if m.profiles[i].IsDefault {
+			m.activeProfile = &m.profiles[i]
+			m.profileStatus = "⬥ Profile: " + m.activeProfile.Name
 			break
 		}
 	}
+	m.reportProfileIndex = 0
+
+	// Build menu items with profiles integrated
+	m.menuItems = buildMenuItems(m.profiles)
+}
 
-	profileStatus := "⬥ No profile selected"
-	if activeProfile != nil {
-		profileStatus = "⬥ Profile: " + activeProfile.Name
+// switchContext makes name the current context and reloads everything tied to the account
+func (m *model) switchContext(name string) {
+	if file, err := loadConfig(); err == nil {
+		file.CurrentContext = name
+		if name == defaultContextName {
+			file.CurrentContext = ""
+		}
+		saveConfig(file)
 	}
 
-	// Build menu items with profiles integrated
-	menuItems := buildMenuItems(profiles)
-
-	return model{
-		menuItems:           menuItems,
-		selectedIndex:       1,
-		profileStatus:       profileStatus,
-		currentScreen:       mainMenuScreen,
-		setupIndex:          0,
-		figmaToken:          cfg.FigmaToken,
-		userID:              cfg.UserID,
-		teamID:              cfg.TeamID,
-		textInput:           ti,
-		editingIndex:        -1,
-		userHandle:          cfg.UserHandle,
-		userEmail:           cfg.UserEmail,
-		fetchingUser:        false,
-		userFetchError:      "",
-		profiles:           profiles,
-		activeProfile:      activeProfile,
-		wizardStep:         wizardTeamID,
-		wizardSelectedProj: make(map[string]bool),
-		loadingState:       notLoading,
-		listCursor:          0,
-		listOffset:          0,
-		showDeleteConfirm:   false,
-		deleteProfileName:   "",
-		reportTimeOptions:   []string{"Last Week", "Last Month", "This Month to Date", "Last 4 Weeks", "Last 30 Days"},
-		reportTimeIndex:     0,
-		reportProfileIndex:  0,
-		generatingReport:    false,
-		reportError:         "",
-		spinnerFrame:        0,
-		spinnerChars:        []string{"⬖", "⬗", "⬘", "⬙"},
+	cfg, err := resolveConfig(name)
+	m.userFetchError = ""
+	if err != nil {
+		m.userFetchError = err.Error()
 	}
+	m.useContext(cfg)
 }
 
 func (m model) saveCurrentConfig() {
-	cfg := config{
-		FigmaToken: m.figmaToken,
-		UserID:     m.userID,
-		TeamID:     m.teamID,
-		UserHandle: m.userHandle,
-		UserEmail:  m.userEmail,
+	// Start from the file on disk so settings the TUI doesn't edit are preserved
+	// Values that only mirror an environment override are left out of it
+	file, _ := loadConfig()
+	cfg, err := file.withContext(m.context)
+	if err != nil {
+		return
+	}
+	if !fromEnv(envFigmaToken, m.figmaToken) {
+		cfg.FigmaToken = m.figmaToken
+	}
+	if !fromEnv(envFigmaUserID, m.userID) {
+		cfg.UserID = m.userID
+		cfg.UserHandle = m.userHandle
+		cfg.UserEmail = m.userEmail
+	}
+	if !fromEnv(envFigmaTeamID, m.teamID) {
+		cfg.TeamID = m.teamID
+	}
+	file.storeContext(m.context, cfg)
+	saveConfig(file)
+}
+
+// client returns a Figma API client for the current token and API settings
+func (m model) client() *FigmaClient {
+	client := NewFigmaClient(m.figmaToken, m.api)
+	// Read the OAuth login fresh: a refresh in an earlier run may have replaced it
+	if cfg, err := resolveConfig(m.context); err == nil {
+		authorize(client, cfg.config, cfg.context)
+	}
+	if !m.noCache {
+		client.Cache, _ = openResponseCache()
+	}
+	m.fixtures.apply(client)
+	return client
+}
+
+// scanOptions builds the scan settings for a TUI report run
+func (m model) scanOptions(profile *Profile) scanOptions {
+	opts := scanOptions{
+		Concurrency:      resolveConcurrency(0, profile, m.concurrency),
+		IncludeTeammates: m.reportTeammates,
+		UserHandle:       m.userHandle,
+		MyCommentsOnly:   m.reportMyComments,
+	}
+	// Recordings and replays must not depend on branches remembered from other runs
+	if !m.fixtures.active() {
+		opts.Branches, _ = openBranchStore()
+	}
+	if m.userID != "" {
+		opts.UserIDs = []string{m.userID}
+	}
+	return opts
+}
+
+// selectedLocation is the zone the selected profile's report windows are counted in
+func (m model) selectedLocation() (*time.Location, error) {
+	return reportLocation("", &m.profiles[m.reportProfileIndex], m.timezone)
+}
+
+// closeCustomRange leaves the custom range form, clearing its date hint from the shared text input
+func (m *model) closeCustomRange() {
+	m.reportCustomField = -1
+	m.textInput.Placeholder = ""
+	m.textInput.Blur()
+}
+
+// loadReportLastRun reads the selected profile's last-run marker while "Since Last Run" is selected,
+// so the report config screen can show where the report would start without reading it on every render
+func (m *model) loadReportLastRun() {
+	m.reportLastRun = ""
+	if reportTimeChoices[m.reportTimeIndex].TimeMode != timeModeSinceLast || len(m.profiles) == 0 {
+		return
 	}
-	saveConfig(cfg)
+
+	last, err := loadLastRun(m.profiles[m.reportProfileIndex].Name)
+	switch {
+	case err != nil:
+		m.reportLastRun = "Last run unknown: " + err.Error()
+	case last.IsZero():
+		m.reportLastRun = fmt.Sprintf("No run yet, covers the last %d days", firstRunDays)
+	default:
+		if location, err := m.selectedLocation(); err == nil {
+			last = last.In(location)
+		}
+		m.reportLastRun = "Since " + last.Format("2006-01-02 15:04")
+	}
+}
+
+// startReport generates a report for the profile selected on the report config screen
+func (m model) startReport(config ReportConfig) (tea.Model, tea.Cmd) {
+	location, err := m.selectedLocation()
+	if err != nil {
+		m.reportTimeError = err.Error()
+		return m, nil
+	}
+	config.Location = location
+	m.reportConfig = config
+
+	// Use the selected profile
+	selectedProfile := &m.profiles[m.reportProfileIndex]
+
+	// Start report generation under a context esc can cancel
+	ctx, cancel := context.WithCancel(context.Background())
+	m.reportRun++
+	m.cancelReport = cancel
+	m.cancellingReport = false
+	m.reportProgress = scanProgress{}
+	m.generatingReport = true
+	m.reportingProfile = selectedProfile
+	m.currentScreen = reportGeneratingScreen
+	m.spinnerFrame = 0
+	// Start both the report generation and the spinner
+	return m, tea.Batch(
+		generateReport(ctx, m.reportRun, m.client(), m.userID, m.userHandle, m.reportConfig, selectedProfile, m.scanOptions(selectedProfile), m.fixtures),
+		tickCmd(),
+	)
 }
 
 func (m model) Init() tea.Cmd {
@@ -583,101 +997,53 @@ func tickCmd() tea.Cmd {
 	})
 }
 
-func fetchUserInfo(token string) tea.Cmd {
+func fetchUserInfo(client *FigmaClient) tea.Cmd {
 	return func() tea.Msg {
-		if token == "" {
+		if !client.hasCredentials() {
 			return userInfoErrMsg{err: "No Figma token set"}
 		}
 
-		client := &http.Client{}
-		req, err := http.NewRequest("GET", "https://api.figma.com/v1/me", nil)
-		if err != nil {
-			return userInfoErrMsg{err: err.Error()}
-		}
-
-		req.Header.Set("X-Figma-Token", token)
-
-		resp, err := client.Do(req)
-		if err != nil {
-			return userInfoErrMsg{err: err.Error()}
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return userInfoErrMsg{err: fmt.Sprintf("API error: %s", string(body))}
-		}
-
-		body, err := io.ReadAll(resp.Body)
+		user, err := client.Me(context.Background())
 		if err != nil {
 			return userInfoErrMsg{err: err.Error()}
 		}
 
-		var result struct {
-			ID     string `json:"id"`
-			Handle string `json:"handle"`
-			Email  string `json:"email"`
-		}
-
-		if err := json.Unmarshal(body, &result); err != nil {
-			return userInfoErrMsg{err: err.Error()}
-		}
-
 		return userInfoMsg{
-			id:     result.ID,
-			handle: result.Handle,
-			email:  result.Email,
+			id:     user.ID,
+			handle: user.Handle,
+			email:  user.Email,
 		}
 	}
 }
 
 // API functions for profile wizard
-func fetchProjects(token, teamID string) tea.Cmd {
+// fetchProjects lists the projects of every team, team by team
+func fetchProjects(client *FigmaClient, teamIDs []string) tea.Cmd {
 	return func() tea.Msg {
-		if token == "" {
+		if !client.hasCredentials() {
 			return projectsErrMsg{err: "No Figma token set"}
 		}
 
-		if teamID == "" {
+		if len(teamIDs) == 0 {
 			return projectsErrMsg{err: "No team ID set"}
 		}
 
-		client := &http.Client{}
-		url := fmt.Sprintf("https://api.figma.com/v1/teams/%s/projects", teamID)
-		req, err := http.NewRequest("GET", url, nil)
-		if err != nil {
-			return projectsErrMsg{err: err.Error()}
-		}
-
-		req.Header.Set("X-Figma-Token", token)
-
-		resp, err := client.Do(req)
-		if err != nil {
-			return projectsErrMsg{err: err.Error()}
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return projectsErrMsg{err: fmt.Sprintf("API error: %s", string(body))}
-		}
-
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return projectsErrMsg{err: err.Error()}
-		}
-
-		var result struct {
-			Projects []FigmaProject `json:"projects"`
-		}
-
-		if err := json.Unmarshal(body, &result); err != nil {
-			return projectsErrMsg{err: err.Error()}
+		var projects []FigmaProject
+		for _, teamID := range teamIDs {
+			teamProjects, err := client.TeamProjects(context.Background(), teamID)
+			if err != nil {
+				if len(teamIDs) > 1 {
+					return projectsErrMsg{err: fmt.Sprintf("team %s: %s", teamID, err.Error())}
+				}
+				return projectsErrMsg{err: err.Error()}
+			}
+			projects = append(projects, teamProjects...)
 		}
 
 		return projectsCompleteMsg{
-			projects: result.Projects,
-			count:    len(result.Projects),
+			projects: projects,
+			count:    len(projects),
+			teams:    len(teamIDs),
 		}
 	}
 }
@@ -705,6 +1071,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.loadingState = notLoading
 		m.loadingError = ""
 		m.loadingProgress = fmt.Sprintf("Found %d projects", msg.count)
+		if msg.teams > 1 {
+			m.loadingProgress = fmt.Sprintf("Found %d projects in %d teams", msg.count, msg.teams)
+		}
 		m.listCursor = 0
 		m.listOffset = 0
 		return m, nil
@@ -714,7 +1083,38 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.loadingError = msg.err
 		return m, nil
 
+	case fileLookupMsg:
+		m.loadingState = notLoading
+		m.loadingError = ""
+		for _, file := range m.wizardFiles {
+			if file.Key == msg.file.Key {
+				m.loadingError = "File is already in the list"
+				return m, nil
+			}
+		}
+		m.wizardFiles = append(m.wizardFiles, msg.file)
+		m.listCursor = len(m.wizardFiles) - 1
+		return m, nil
+
+	case fileLookupErrMsg:
+		m.loadingState = notLoading
+		m.loadingError = msg.err
+		return m, nil
+
+	case reportProgressMsg:
+		if msg.run != m.reportRun || !m.generatingReport {
+			return m, nil
+		}
+		m.reportProgress = msg.progress
+		return m, waitForReportProgress(msg.run, msg.updates)
+
 	case reportGeneratedMsg:
+		if msg.run != m.reportRun || !m.generatingReport {
+			return m, nil
+		}
+		m.cancelReport()
+		m.cancelReport = nil
+		m.cancellingReport = false
 		m.generatingReport = false
 		m.activityReport = msg.report
 		m.reportContent = msg.content
@@ -735,6 +1135,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, exportReport(msg.content, profileName)
 
 	case reportErrMsg:
+		if msg.run != m.reportRun || !m.generatingReport {
+			return m, nil
+		}
+		m.cancelReport()
+		m.cancelReport = nil
+		m.cancellingReport = false
 		m.generatingReport = false
 		m.reportError = msg.err
 		m.currentScreen = reportViewScreen
@@ -769,6 +1175,56 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case tea.KeyMsg:
+		// Handle the custom range form of the report config screen
+		if m.currentScreen == reportConfigScreen && m.reportCustomField >= 0 {
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "esc":
+				// Close the form, keeping what was entered
+				m.closeCustomRange()
+				return m, nil
+			case "enter":
+				value := strings.TrimSpace(m.textInput.Value())
+				if m.reportCustomField == 0 {
+					m.reportCustomFrom = value
+					m.reportCustomField = 1
+					m.textInput.SetValue(m.reportCustomTo)
+					m.textInput.Placeholder = "today"
+					m.textInput.CursorEnd()
+					return m, nil
+				}
+
+				m.reportCustomTo = value
+				location, err := m.selectedLocation()
+				if err != nil {
+					m.reportTimeError = err.Error()
+					return m, nil
+				}
+				now := time.Now().In(location)
+				config, err := customRange(m.reportCustomFrom, m.reportCustomTo, now)
+				if err != nil {
+					// Reopen the field at fault: To if it doesn't parse, otherwise From
+					m.reportTimeError = err.Error()
+					if _, _, toErr := parseDay(m.reportCustomTo, now); m.reportCustomTo != "" && toErr != nil {
+						return m, nil
+					}
+					m.reportCustomField = 0
+					m.textInput.SetValue(m.reportCustomFrom)
+					m.textInput.Placeholder = "2026-09-01"
+					m.textInput.CursorEnd()
+					return m, nil
+				}
+				m.closeCustomRange()
+				m.reportTimeError = ""
+				return m.startReport(config)
+			default:
+				var cmd tea.Cmd
+				m.textInput, cmd = m.textInput.Update(msg)
+				return m, cmd
+			}
+		}
+
 		// Handle report config screen
 		if m.currentScreen == reportConfigScreen {
 			switch msg.String() {
@@ -783,22 +1239,36 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Navigate profiles
 				if len(m.profiles) > 0 && m.reportProfileIndex > 0 {
 					m.reportProfileIndex--
+					m.reportTimeError = ""
+					m.loadReportLastRun()
 				}
 			case "right", "l":
 				// Navigate profiles
 				if len(m.profiles) > 0 && m.reportProfileIndex < len(m.profiles)-1 {
 					m.reportProfileIndex++
+					m.reportTimeError = ""
+					m.loadReportLastRun()
 				}
 			case "up", "k":
 				// Navigate time options
 				if m.reportTimeIndex > 0 {
 					m.reportTimeIndex--
+					m.reportTimeError = ""
+					m.loadReportLastRun()
 				}
 			case "down", "j":
 				// Navigate time options
 				if m.reportTimeIndex < len(m.reportTimeOptions)-1 {
 					m.reportTimeIndex++
+					m.reportTimeError = ""
+					m.loadReportLastRun()
 				}
+			case "t":
+				// Toggle teammate-only files
+				m.reportTeammates = !m.reportTeammates
+			case "c":
+				// Toggle comment filter
+				m.reportMyComments = !m.reportMyComments
 			case "enter":
 				// Validate profile selected
 				if len(m.profiles) == 0 {
@@ -807,37 +1277,37 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 				// Generate report based on selected time mode
-				var selectedMode timeMode
-				switch m.reportTimeIndex {
-				case 0:
-					selectedMode = timeModeLastWeek
-				case 1:
-					selectedMode = timeModeLastMonth
-				case 2:
-					selectedMode = timeModeThisMonthToDate
-				case 3:
-					selectedMode = timeModeLast4Weeks
-				case 4:
-					selectedMode = timeModeLast30Days
-				}
-
-				m.reportConfig = ReportConfig{
-					TimeMode: selectedMode,
+				config := reportTimeChoices[m.reportTimeIndex]
+				switch config.TimeMode {
+				case timeModeSprint:
+					// Sprints are defined per profile
+					config.Sprint = m.profiles[m.reportProfileIndex].Sprint
+					if err := config.Sprint.validate(); err != nil {
+						m.reportTimeError = err.Error()
+						return m, nil
+					}
+				case timeModeSinceLast:
+					// Picks up where the profile's last since-last report ended
+					last, err := loadLastRun(m.profiles[m.reportProfileIndex].Name)
+					if err != nil {
+						m.reportTimeError = "Failed to read the last run: " + err.Error()
+						return m, nil
+					}
+					config.From = last
+				case timeModeCustom:
+					// Custom range: ask for the dates first
+					m.reportCustomField = 0
+					m.reportTimeError = ""
+					m.textInput.SetValue(m.reportCustomFrom)
+					m.textInput.Placeholder = "2026-09-01"
+					m.textInput.Width = 30
+					m.textInput.CursorEnd()
+					m.textInput.Focus()
+					return m, nil
 				}
 
-				// Use the selected profile
-				selectedProfile := &m.profiles[m.reportProfileIndex]
-
-				// Start report generation
-				m.generatingReport = true
-				m.reportingProfile = selectedProfile
-				m.currentScreen = reportGeneratingScreen
-				m.spinnerFrame = 0
-				// Start both the report generation and the spinner
-				return m, tea.Batch(
-					generateReport(m.figmaToken, m.userID, m.userHandle, m.teamID, m.reportConfig, selectedProfile),
-					tickCmd(),
-				)
+				m.reportTimeError = ""
+				return m.startReport(config)
 			}
 			return m, nil
 		}
@@ -848,7 +1318,19 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case "ctrl+c":
 				return m, tea.Quit
 			case "esc":
+				// First esc stops the scan; the partial report is shown once workers wind down
+				if m.generatingReport && !m.cancellingReport {
+					m.cancelReport()
+					m.cancellingReport = true
+					return m, nil
+				}
+
 				// Back to main menu
+				if m.cancelReport != nil {
+					m.cancelReport()
+					m.cancelReport = nil
+				}
+				m.cancellingReport = false
 				m.currentScreen = mainMenuScreen
 				m.selectedIndex = 1
 				m.generatingReport = false
@@ -881,13 +1363,16 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.previewProfile != nil {
 					m.wizardEditMode = true
 					m.wizardStep = wizardTeamID
-					m.wizardTeamID = m.previewProfile.TeamID
+					m.wizardTeamID = strings.Join(m.previewProfile.teamIDs(), ", ")
 					m.wizardProfileName = m.previewProfile.Name
 					// Pre-select current projects
 					m.wizardSelectedProj = make(map[string]bool)
 					for _, proj := range m.previewProfile.SelectedProjects {
 						m.wizardSelectedProj[proj.ID] = true
 					}
+					m.wizardFiles = append([]ProfileFile(nil), m.previewProfile.Files...)
+					m.wizardInclude = append([]string(nil), m.previewProfile.Include...)
+					m.wizardExclude = append([]string(nil), m.previewProfile.Exclude...)
 					m.wizardProjects = nil
 					m.loadingState = notLoading
 					m.loadingError = ""
@@ -934,12 +1419,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 
 					// Check if profile name already exists (skip if in edit mode with same name)
+					// Profile files are shared by all contexts, so look on disk rather than in m.profiles
 					if !m.wizardEditMode || (m.wizardEditMode && profileName != m.previewProfile.Name) {
-						for _, p := range m.profiles {
-							if p.Name == profileName {
-								m.loadingError = "Profile name already exists"
-								return m, nil
-							}
+						if _, err := loadProfile(profileName); err == nil {
+							m.loadingError = "Profile name already exists"
+							return m, nil
 						}
 					}
 
@@ -948,8 +1432,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					for _, project := range m.wizardProjects {
 						if m.wizardSelectedProj[project.ID] {
 							selectedProjects = append(selectedProjects, ProfileProject{
-								ID:   project.ID,
-								Name: project.Name,
+								ID:       project.ID,
+								Name:     project.Name,
+								TeamID:   project.TeamID,
+								TeamName: project.TeamName,
 							})
 						}
 					}
@@ -957,37 +1443,52 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					var profile Profile
 					if m.wizardEditMode && m.previewProfile != nil {
 						// Update existing profile
-						// If name changed, delete old profile file
-						if profileName != m.previewProfile.Name {
-							deleteProfile(m.previewProfile.Name)
-						}
-
 						profile = Profile{
 							Name:             profileName,
-							TeamID:           m.wizardTeamID,
 							SelectedProjects: selectedProjects,
+							Files:            m.wizardFiles,
+							Include:          m.wizardInclude,
+							Exclude:          m.wizardExclude,
 							CreatedAt:        m.previewProfile.CreatedAt, // Preserve original creation time
 							IsDefault:        m.previewProfile.IsDefault, // Preserve default status
+							Concurrency:      m.previewProfile.Concurrency,
+							Context:          m.previewProfile.Context,
+							Sprint:           m.previewProfile.Sprint,
+							Timezone:         m.previewProfile.Timezone,
 						}
 					} else {
 						// Create new profile
 						profile = Profile{
 							Name:             profileName,
-							TeamID:           m.wizardTeamID,
 							SelectedProjects: selectedProjects,
+							Files:            m.wizardFiles,
+							Include:          m.wizardInclude,
+							Exclude:          m.wizardExclude,
 							CreatedAt:        time.Now(),
 							IsDefault:        len(m.profiles) == 0, // First profile is default
+							Context:          profileContext(m.context),
 						}
 					}
 
+					// Older versions read only the profile's team, so keep the first one there
+					if teams := profile.teamIDs(); len(teams) > 0 {
+						profile.TeamID = teams[0]
+					}
+
 					// Save profile
 					if err := saveProfile(profile); err != nil {
 						m.loadingError = "Failed to save profile: " + err.Error()
 						return m, nil
 					}
 
+					// If name changed, delete the old profile file only once the new one is saved
+					if m.wizardEditMode && m.previewProfile != nil && profileName != m.previewProfile.Name {
+						deleteProfile(m.previewProfile.Name)
+						moveLastRun(m.previewProfile.Name, profileName)
+					}
+
 					// Reload profiles
-					profiles, _ := loadAllProfiles()
+					profiles, _ := loadContextProfiles(m.context)
 					m.profiles = profiles
 
 					// Update active profile if it was being edited
@@ -1020,8 +1521,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
-			// If editing Team ID
-			if m.wizardStep == wizardTeamID && m.editingIndex == 0 {
+			// If adding a watched file
+			if m.wizardStep == wizardFiles && m.editingIndex == 0 {
 				switch msg.String() {
 				case "ctrl+c":
 					return m, tea.Quit
@@ -1031,24 +1532,57 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.editingIndex = -1
 					return m, nil
 				case "enter":
-					// Save value and move to next step
-					m.wizardTeamID = m.textInput.Value()
+					// Look the file up before adding it, so typos are caught now rather than in every report
+					value := strings.TrimSpace(m.textInput.Value())
 					m.textInput.SetValue("")
 					m.editingIndex = -1
-
-					// Validate team ID is set
-					if m.wizardTeamID == "" {
-						m.loadingError = "Team ID is required"
+					if value == "" {
 						return m, nil
 					}
+					m.loadingState = loadingFile
+					m.loadingError = ""
+					return m, lookupFile(m.client(), value)
+				default:
+					// Pass input to textinput
+					m.textInput, cmd = m.textInput.Update(msg)
+					return m, cmd
+				}
+			}
 
-					// Move to projects step and fetch projects
-					m.wizardStep = wizardProjects
-					m.loadingState = loadingProjects
+			// If adding an include (0) or exclude (1) rule
+			if m.wizardStep == wizardFilters && m.editingIndex >= 0 {
+				switch msg.String() {
+				case "ctrl+c":
+					return m, tea.Quit
+				case "esc":
+					// Cancel editing
+					m.textInput.SetValue("")
+					m.editingIndex = -1
+					return m, nil
+				case "enter":
+					rule := strings.TrimSpace(m.textInput.Value())
+					if rule == "" {
+						m.textInput.SetValue("")
+						m.editingIndex = -1
+						return m, nil
+					}
+					// Keep the input open on a bad rule so it can be fixed
+					if _, err := compileNameRule(rule); err != nil {
+						m.loadingError = err.Error()
+						return m, nil
+					}
+					// Includes are listed before excludes; put the cursor on the new rule
+					if m.editingIndex == 0 {
+						m.wizardInclude = append(m.wizardInclude, rule)
+						m.listCursor = len(m.wizardInclude) - 1
+					} else {
+						m.wizardExclude = append(m.wizardExclude, rule)
+						m.listCursor = len(m.wizardInclude) + len(m.wizardExclude) - 1
+					}
+					m.textInput.SetValue("")
+					m.editingIndex = -1
 					m.loadingError = ""
-					m.listCursor = 0
-					m.listOffset = 0
-					return m, fetchProjects(m.figmaToken, m.wizardTeamID)
+					return m, nil
 				default:
 					// Pass input to textinput
 					m.textInput, cmd = m.textInput.Update(msg)
@@ -1056,11 +1590,49 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
-			// Not editing
-			switch msg.String() {
-			case "ctrl+c":
-				return m, tea.Quit
-			case "esc":
+			// If editing Team ID
+			if m.wizardStep == wizardTeamID && m.editingIndex == 0 {
+				switch msg.String() {
+				case "ctrl+c":
+					return m, tea.Quit
+				case "esc":
+					// Cancel editing
+					m.textInput.SetValue("")
+					m.editingIndex = -1
+					return m, nil
+				case "enter":
+					// Save value and move to next step
+					m.wizardTeamID = m.textInput.Value()
+					m.textInput.SetValue("")
+					m.editingIndex = -1
+
+					// Validate team ID is set
+					teamIDs := splitList(m.wizardTeamID)
+					if len(teamIDs) == 0 {
+						m.loadingError = "Team ID is required"
+						return m, nil
+					}
+					m.wizardTeamID = strings.Join(teamIDs, ", ")
+
+					// Move to projects step and fetch projects
+					m.wizardStep = wizardProjects
+					m.loadingState = loadingProjects
+					m.loadingError = ""
+					m.listCursor = 0
+					m.listOffset = 0
+					return m, fetchProjects(m.client(), teamIDs)
+				default:
+					// Pass input to textinput
+					m.textInput, cmd = m.textInput.Update(msg)
+					return m, cmd
+				}
+			}
+
+			// Not editing
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "esc":
 				// Cancel wizard and go back to manage profiles
 				m.currentScreen = manageProfilesScreen
 				m.listCursor = 0
@@ -1077,6 +1649,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							m.listOffset = m.listCursor
 						}
 					}
+				} else if (m.wizardStep == wizardFiles || m.wizardStep == wizardFilters) && m.listCursor > 0 {
+					m.listCursor--
 				}
 			case "down", "j":
 				// Handle project list navigation
@@ -1088,6 +1662,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							m.listOffset = m.listCursor - 10 + 1
 						}
 					}
+				} else if m.wizardStep == wizardFiles && m.listCursor < len(m.wizardFiles)-1 {
+					m.listCursor++
+				} else if m.wizardStep == wizardFilters && m.listCursor < len(m.wizardInclude)+len(m.wizardExclude)-1 {
+					m.listCursor++
 				}
 			case " ":
 				// Toggle selection for projects
@@ -1099,6 +1677,62 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						m.wizardSelectedProj[project.ID] = true
 					}
 				}
+			case "a":
+				// Add a watched file by key or URL
+				if m.wizardStep == wizardFiles && m.loadingState == notLoading {
+					m.editingIndex = 0
+					m.textInput.SetValue("")
+					// Adjust text input width to fit terminal
+					inputWidth := m.width - 8 // Account for padding and margins
+					if inputWidth > 80 {
+						inputWidth = 80
+					}
+					if inputWidth < 20 {
+						inputWidth = 20
+					}
+					m.textInput.Width = inputWidth
+					m.textInput.Focus()
+				}
+			case "i", "e":
+				// Add an include or exclude rule
+				if m.wizardStep == wizardFilters {
+					m.editingIndex = 0
+					if msg.String() == "e" {
+						m.editingIndex = 1
+					}
+					m.loadingError = ""
+					m.textInput.SetValue("")
+					// Adjust text input width to fit terminal
+					inputWidth := m.width - 8 // Account for padding and margins
+					if inputWidth > 80 {
+						inputWidth = 80
+					}
+					if inputWidth < 20 {
+						inputWidth = 20
+					}
+					m.textInput.Width = inputWidth
+					m.textInput.Focus()
+				}
+			case "x", "delete", "backspace":
+				// Remove the selected watched file
+				if m.wizardStep == wizardFiles && m.listCursor < len(m.wizardFiles) {
+					m.wizardFiles = append(m.wizardFiles[:m.listCursor:m.listCursor], m.wizardFiles[m.listCursor+1:]...)
+					if m.listCursor > 0 && m.listCursor >= len(m.wizardFiles) {
+						m.listCursor--
+					}
+				}
+				// Remove the selected rule; includes come first in the list
+				if m.wizardStep == wizardFilters && m.listCursor < len(m.wizardInclude)+len(m.wizardExclude) {
+					if m.listCursor < len(m.wizardInclude) {
+						m.wizardInclude = append(m.wizardInclude[:m.listCursor:m.listCursor], m.wizardInclude[m.listCursor+1:]...)
+					} else {
+						i := m.listCursor - len(m.wizardInclude)
+						m.wizardExclude = append(m.wizardExclude[:i:i], m.wizardExclude[i+1:]...)
+					}
+					if m.listCursor > 0 && m.listCursor >= len(m.wizardInclude)+len(m.wizardExclude) {
+						m.listCursor--
+					}
+				}
 			case "enter":
 				if m.wizardStep == wizardTeamID && m.editingIndex == -1 {
 					// Start editing team ID
@@ -1116,21 +1750,34 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.textInput.Focus()
 					return m, nil
 				} else if m.wizardStep == wizardProjects {
-					// Validate at least one project selected
-					if len(m.wizardSelectedProj) == 0 {
-						m.loadingError = "Please select at least one project"
-						return m, nil
-					}
-
-					// Collect selected project IDs
-					var selectedProjectIDs []string
+					// Projects are optional when the profile watches individual files
+					m.wizardStep = wizardFiles
+					m.loadingError = ""
+					m.editingIndex = -1
+					m.listCursor = 0
+					m.listOffset = 0
+					return m, nil
+				} else if m.wizardStep == wizardFiles && m.loadingState == notLoading {
+					// Validate at least one project or file selected
+					selectedCount := 0
 					for _, project := range m.wizardProjects {
 						if m.wizardSelectedProj[project.ID] {
-							selectedProjectIDs = append(selectedProjectIDs, project.ID)
+							selectedCount++
 						}
 					}
+					if selectedCount == 0 && len(m.wizardFiles) == 0 {
+						m.loadingError = "Please select at least one project or add a file"
+						return m, nil
+					}
 
-					// Move directly to save name step
+					// Move to filters step
+					m.wizardStep = wizardFilters
+					m.loadingError = ""
+					m.editingIndex = -1
+					m.listCursor = 0
+					return m, nil
+				} else if m.wizardStep == wizardFilters {
+					// Move to save name step
 					m.wizardStep = wizardSaveName
 					m.loadingError = ""
 					m.editingIndex = -1
@@ -1164,8 +1811,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					// Confirm delete
 					err := deleteProfile(m.deleteProfileName)
 					if err == nil {
+						deleteLastRun(m.deleteProfileName)
+
 						// Reload profiles
-						profiles, _ := loadAllProfiles()
+						profiles, _ := loadContextProfiles(m.context)
 						m.profiles = profiles
 
 						// If deleted profile was active, clear it
@@ -1174,8 +1823,8 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							m.profileStatus = "⬥ No profile selected"
 							// Set first remaining profile as default if any exist
 							if len(m.profiles) > 0 {
-								setDefaultProfile(m.profiles[0].Name)
-								profiles, _ = loadAllProfiles()
+								setDefaultProfile(m.profiles[0].Name, m.context)
+								profiles, _ = loadContextProfiles(m.context)
 								m.profiles = profiles
 								for i := range m.profiles {
 									if m.profiles[i].IsDefault {
@@ -1237,9 +1886,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.listCursor > 0 && m.listCursor <= len(m.profiles) {
 					profileIndex := m.listCursor - 1
 					selectedProfile := m.profiles[profileIndex]
-					setDefaultProfile(selectedProfile.Name)
+					setDefaultProfile(selectedProfile.Name, m.context)
 					// Reload profiles
-					profiles, _ := loadAllProfiles()
+					profiles, _ := loadContextProfiles(m.context)
 					m.profiles = profiles
 					for i := range m.profiles {
 						if m.profiles[i].IsDefault {
@@ -1259,6 +1908,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.wizardStep = wizardTeamID
 					m.wizardTeamID = m.teamID
 					m.wizardSelectedProj = make(map[string]bool)
+					m.wizardFiles = nil
+					m.wizardInclude = nil
+					m.wizardExclude = nil
 					m.wizardProfileName = ""
 					m.wizardEditMode = false
 					m.loadingState = notLoading
@@ -1331,6 +1983,23 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.setupIndex < 4 { // 4 settings + back option = 5 items (0-4)
 					m.setupIndex++
 				}
+			case "left", "h", "right", "l":
+				// Switch context
+				current := 0
+				for i, name := range m.contexts {
+					if name == m.context {
+						current = i
+					}
+				}
+				next := current
+				if (msg.String() == "left" || msg.String() == "h") && current > 0 {
+					next--
+				} else if (msg.String() == "right" || msg.String() == "l") && current < len(m.contexts)-1 {
+					next++
+				}
+				if next != current {
+					m.switchContext(m.contexts[next])
+				}
 			case "enter":
 				switch m.setupIndex {
 				case 0: // Set Figma Token
@@ -1349,7 +2018,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				case 1: // Set User ID - Gather user info from API
 					m.fetchingUser = true
 					m.userFetchError = ""
-					return m, fetchUserInfo(m.figmaToken)
+					return m, fetchUserInfo(m.client())
 				case 2: // Set Team ID
 					m.editingIndex = 2
 					m.textInput.SetValue(m.teamID)
@@ -1413,6 +2082,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							}
 						}
 					}
+					m.loadReportLastRun()
 				} else if selectedTitle == "Setup" {
 					m.currentScreen = setupScreen
 					m.setupIndex = 0
@@ -1427,9 +2097,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					profileName = strings.TrimSuffix(profileName, " (default)")
 
 					// Set as active profile
-					setDefaultProfile(profileName)
+					setDefaultProfile(profileName, m.context)
 					// Reload profiles
-					profiles, _ := loadAllProfiles()
+					profiles, _ := loadContextProfiles(m.context)
 					m.profiles = profiles
 					for i := range m.profiles {
 						if m.profiles[i].IsDefault {
@@ -1619,11 +2289,17 @@ func (m model) viewSetupScreen() string {
 		userIDValue = "Gather"
 	}
 
+	// An OAuth login replaces the personal access token
+	tokenValue := m.figmaToken
+	if m.oauthLoggedIn {
+		tokenValue = "Logged in with OAuth"
+	}
+
 	setupItems := []struct {
 		title string
 		value string
 	}{
-		{"Set Figma Token", m.figmaToken},
+		{"Set Figma Token", tokenValue},
 		{"Set User ID", userIDValue},
 		{"Set Team ID", m.teamID},
 		{"← Back", "Back to main screen"},
@@ -1633,6 +2309,34 @@ func (m model) viewSetupScreen() string {
 	var menuStrings []string
 	menuStrings = append(menuStrings, "") // Empty line at top
 
+	// Context switcher, shown once there is more than one account
+	if len(m.contexts) > 1 {
+		current := 0
+		for i, name := range m.contexts {
+			if name == m.context {
+				current = i
+			}
+		}
+
+		var contextParts []string
+		contextParts = append(contextParts, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("  Context:"))
+		if current > 0 {
+			contextParts = append(contextParts, lipgloss.NewStyle().Foreground(cyanColor).Render(" ◀ "))
+		} else {
+			contextParts = append(contextParts, lipgloss.NewStyle().Foreground(dimWhiteColor).Render(" ◀ "))
+		}
+		contextParts = append(contextParts, lipgloss.NewStyle().Foreground(whiteColor).Bold(true).Render(m.context))
+		if current < len(m.contexts)-1 {
+			contextParts = append(contextParts, lipgloss.NewStyle().Foreground(cyanColor).Render(" ▶"))
+		} else {
+			contextParts = append(contextParts, lipgloss.NewStyle().Foreground(dimWhiteColor).Render(" ▶"))
+		}
+		contextParts = append(contextParts, lipgloss.NewStyle().Foreground(grayColor).Render(fmt.Sprintf("  (%d/%d)", current+1, len(m.contexts))))
+
+		menuStrings = append(menuStrings, strings.Join(contextParts, ""))
+		menuStrings = append(menuStrings, "")
+	}
+
 	// Display user info if available
 	if m.userHandle != "" && m.userID != "" {
 		userInfoStyle := lipgloss.NewStyle().
@@ -1762,6 +2466,11 @@ func (m model) viewSetupScreen() string {
 	ctrlCDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("quit")
 
 	leftShortcuts := lipgloss.JoinHorizontal(lipgloss.Top, escStyle, " ", escDesc, "    ", ctrlCStyle, " ", ctrlCDesc)
+	if len(m.contexts) > 1 {
+		arrowsStyle := lipgloss.NewStyle().Foreground(cyanColor).Render("←/→")
+		arrowsDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("switch context")
+		leftShortcuts = lipgloss.JoinHorizontal(lipgloss.Top, arrowsStyle, " ", arrowsDesc, "    ", leftShortcuts)
+	}
 
 	dots := ""
 	for _, color := range gradientColors {
@@ -2050,7 +2759,7 @@ func (m model) viewProfileWizard() string {
 		step1Indicator = "✓"
 		step1Style = lipgloss.NewStyle().Foreground(greenColor)
 	}
-	stepParts = append(stepParts, step1Style.Render(step1Indicator+" Team ID"))
+	stepParts = append(stepParts, step1Style.Render(step1Indicator+" Teams"))
 	stepParts = append(stepParts, chevronStyle.Render(" ❯ "))
 
 	// Step 2: Projects
@@ -2066,14 +2775,40 @@ func (m model) viewProfileWizard() string {
 	stepParts = append(stepParts, step2Style.Render(step2Indicator+" Projects"))
 	stepParts = append(stepParts, chevronStyle.Render(" ❯ "))
 
-	// Step 3: Save
+	// Step 3: Files
 	step3Style := lipgloss.NewStyle().Foreground(dimWhiteColor)
 	step3Indicator := "○"
-	if m.wizardStep == wizardSaveName {
+	if m.wizardStep == wizardFiles {
 		step3Indicator = "●"
 		step3Style = lipgloss.NewStyle().Foreground(whiteColor).Bold(true)
+	} else if m.wizardStep > wizardFiles {
+		step3Indicator = "✓"
+		step3Style = lipgloss.NewStyle().Foreground(greenColor)
 	}
-	stepParts = append(stepParts, step3Style.Render(step3Indicator+" Save"))
+	stepParts = append(stepParts, step3Style.Render(step3Indicator+" Files"))
+	stepParts = append(stepParts, chevronStyle.Render(" ❯ "))
+
+	// Step 4: Filters
+	step4Style := lipgloss.NewStyle().Foreground(dimWhiteColor)
+	step4Indicator := "○"
+	if m.wizardStep == wizardFilters {
+		step4Indicator = "●"
+		step4Style = lipgloss.NewStyle().Foreground(whiteColor).Bold(true)
+	} else if m.wizardStep > wizardFilters {
+		step4Indicator = "✓"
+		step4Style = lipgloss.NewStyle().Foreground(greenColor)
+	}
+	stepParts = append(stepParts, step4Style.Render(step4Indicator+" Filters"))
+	stepParts = append(stepParts, chevronStyle.Render(" ❯ "))
+
+	// Step 5: Save
+	step5Style := lipgloss.NewStyle().Foreground(dimWhiteColor)
+	step5Indicator := "○"
+	if m.wizardStep == wizardSaveName {
+		step5Indicator = "●"
+		step5Style = lipgloss.NewStyle().Foreground(whiteColor).Bold(true)
+	}
+	stepParts = append(stepParts, step5Style.Render(step5Indicator+" Save"))
 
 	stepsLine := "  " + strings.Join(stepParts, "")
 	menuStrings = append(menuStrings, stepsLine)
@@ -2083,6 +2818,7 @@ func (m model) viewProfileWizard() string {
 	switch m.wizardStep {
 	case wizardTeamID:
 		menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(defaultTextColor).Render("  Team ID:"))
+		menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("  Separate several team IDs with commas"))
 		menuStrings = append(menuStrings, "")
 
 		// Show input field
@@ -2132,9 +2868,26 @@ func (m model) viewProfileWizard() string {
 				endIdx = totalItems
 			}
 
+			// Group by team when projects come from several teams
+			multiTeam := false
+			for _, project := range m.wizardProjects {
+				if project.TeamID != m.wizardProjects[0].TeamID {
+					multiTeam = true
+					break
+				}
+			}
+
 			// Render visible project list
 			for i := startIdx; i < endIdx; i++ {
 				project := m.wizardProjects[i]
+				if multiTeam && (i == startIdx || project.TeamID != m.wizardProjects[i-1].TeamID) {
+					if i != startIdx {
+						menuStrings = append(menuStrings, "")
+					}
+					teamHeader := "  " + teamLabel(project.TeamID, project.TeamName)
+					menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(cyanColor).Render(teamHeader))
+				}
+
 				var marker string
 				var itemStyle lipgloss.Style
 
@@ -2168,6 +2921,97 @@ func (m model) viewProfileWizard() string {
 			}
 		}
 
+	case wizardFiles:
+		headerText := fmt.Sprintf("  Watched files (%d):", len(m.wizardFiles))
+		menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(defaultTextColor).Render(headerText))
+		menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("  Optional. Watched files are scanned directly, without listing their projects"))
+		menuStrings = append(menuStrings, "")
+
+		if len(m.wizardFiles) == 0 {
+			menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("  No files added"))
+		}
+		for i, file := range m.wizardFiles {
+			itemStyle := lipgloss.NewStyle().Foreground(defaultTextColor)
+			marker := "  "
+			if i == m.listCursor && m.editingIndex == -1 {
+				itemStyle = itemStyle.Bold(true).Foreground(whiteColor)
+				marker = "➤ "
+			}
+			line := itemStyle.Render("  "+marker+file.Name) + " " + lipgloss.NewStyle().Foreground(grayColor).Render("("+file.Key+")")
+			menuStrings = append(menuStrings, line)
+		}
+		menuStrings = append(menuStrings, "")
+
+		if m.editingIndex == 0 {
+			menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(defaultTextColor).Render("  File key or Figma URL:"))
+			inputStyle := lipgloss.NewStyle().
+				Background(grayColor).
+				Foreground(whiteColor)
+			menuStrings = append(menuStrings, "  "+inputStyle.Render(m.textInput.View()))
+			menuStrings = append(menuStrings, "")
+		} else if m.loadingState == loadingFile {
+			menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(cyanColor).Render("  Looking up file..."))
+			menuStrings = append(menuStrings, "")
+		}
+
+		if m.loadingError != "" {
+			menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(lipgloss.Color("#ea4536")).Render("  Error: "+m.loadingError))
+			menuStrings = append(menuStrings, "")
+		}
+
+	case wizardFilters:
+		menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(defaultTextColor).Render("  File name rules:"))
+		menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("  Optional. Globs like Archive*, [OLD]* or Untitled, or /regular expressions/"))
+		menuStrings = append(menuStrings, "")
+
+		// Includes and excludes share one cursor, includes first
+		sections := []struct {
+			label string
+			rules []string
+			start int
+		}{
+			{"Include", m.wizardInclude, 0},
+			{"Exclude", m.wizardExclude, len(m.wizardInclude)},
+		}
+		for _, section := range sections {
+			menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(cyanColor).Render("  "+section.label+":"))
+			if len(section.rules) == 0 {
+				none := "  None"
+				if section.label == "Include" {
+					none = "  None (all files)"
+				}
+				menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("  "+none))
+			}
+			for i, rule := range section.rules {
+				itemStyle := lipgloss.NewStyle().Foreground(defaultTextColor)
+				marker := "  "
+				if section.start+i == m.listCursor && m.editingIndex == -1 {
+					itemStyle = itemStyle.Bold(true).Foreground(whiteColor)
+					marker = "➤ "
+				}
+				menuStrings = append(menuStrings, itemStyle.Render("  "+marker+rule))
+			}
+			menuStrings = append(menuStrings, "")
+		}
+
+		if m.editingIndex >= 0 {
+			label := "  Include rule (glob or /regex/):"
+			if m.editingIndex == 1 {
+				label = "  Exclude rule (glob or /regex/):"
+			}
+			menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(defaultTextColor).Render(label))
+			inputStyle := lipgloss.NewStyle().
+				Background(grayColor).
+				Foreground(whiteColor)
+			menuStrings = append(menuStrings, "  "+inputStyle.Render(m.textInput.View()))
+			menuStrings = append(menuStrings, "")
+		}
+
+		if m.loadingError != "" {
+			menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(lipgloss.Color("#ea4536")).Render("  Error: "+m.loadingError))
+			menuStrings = append(menuStrings, "")
+		}
+
 	case wizardSaveName:
 		menuStrings = append(menuStrings, lipgloss.NewStyle().Foreground(defaultTextColor).Render("  Profile name:"))
 		menuStrings = append(menuStrings, "")
@@ -2219,6 +3063,35 @@ func (m model) viewProfileWizard() string {
 			escStyle, " ", escDesc, "    ",
 			spaceStyle, " ", spaceDesc, "    ",
 			enterStyle, " ", enterDesc)
+	} else if m.wizardStep == wizardFiles {
+		// Show add and remove shortcuts for the watchlist
+		aStyle := lipgloss.NewStyle().Foreground(cyanColor).Render("a")
+		aDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("add file")
+		xStyle := lipgloss.NewStyle().Foreground(cyanColor).Render("x")
+		xDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("remove")
+		enterDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("continue")
+
+		leftShortcuts = lipgloss.JoinHorizontal(lipgloss.Top,
+			escStyle, " ", escDesc, "    ",
+			aStyle, " ", aDesc, "    ",
+			xStyle, " ", xDesc, "    ",
+			enterStyle, " ", enterDesc)
+	} else if m.wizardStep == wizardFilters {
+		// Show include, exclude and remove shortcuts for the rules
+		iStyle := lipgloss.NewStyle().Foreground(cyanColor).Render("i")
+		iDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("include")
+		eStyle := lipgloss.NewStyle().Foreground(cyanColor).Render("e")
+		eDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("exclude")
+		xStyle := lipgloss.NewStyle().Foreground(cyanColor).Render("x")
+		xDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("remove")
+		enterDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("continue")
+
+		leftShortcuts = lipgloss.JoinHorizontal(lipgloss.Top,
+			escStyle, " ", escDesc, "    ",
+			iStyle, " ", iDesc, "    ",
+			eStyle, " ", eDesc, "    ",
+			xStyle, " ", xDesc, "    ",
+			enterStyle, " ", enterDesc)
 	} else if m.wizardStep == wizardTeamID || m.wizardStep == wizardSaveName {
 		// Show enter shortcut for input screens
 		enterDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("edit")
@@ -2328,28 +3201,86 @@ func (m model) viewProfilePreview() string {
 	// Team ID
 	labelStyle := lipgloss.NewStyle().Foreground(dimWhiteColor)
 	valueStyle := lipgloss.NewStyle().Foreground(defaultTextColor)
-	contentStrings = append(contentStrings, labelStyle.Render("  Team ID: ")+valueStyle.Render(m.previewProfile.TeamID))
+	teamIDs := m.previewProfile.teamIDs()
+	if len(teamIDs) > 1 {
+		contentStrings = append(contentStrings, labelStyle.Render("  Teams: ")+valueStyle.Render(strings.Join(teamIDs, ", ")))
+	} else {
+		contentStrings = append(contentStrings, labelStyle.Render("  Team ID: ")+valueStyle.Render(m.previewProfile.TeamID))
+	}
 	contentStrings = append(contentStrings, "")
 
-	// Display projects list
+	// Display projects list, grouped by team when there are several
 	contentStrings = append(contentStrings, labelStyle.Render("  Projects:"))
 	darkGreyColor := lipgloss.Color("#666666")
 	idStyle := lipgloss.NewStyle().Foreground(darkGreyColor)
 
-	for i, project := range m.previewProfile.SelectedProjects {
-		// Determine if this is the last project
-		isLastProject := (i == len(m.previewProfile.SelectedProjects)-1)
-		var projectPrefix string
+	for _, teamID := range teamIDs {
+		var teamProjects []ProfileProject
+		for _, project := range m.previewProfile.SelectedProjects {
+			if project.TeamID == teamID || (project.TeamID == "" && teamID == m.previewProfile.TeamID) {
+				teamProjects = append(teamProjects, project)
+			}
+		}
 
-		if isLastProject {
-			projectPrefix = "  └╼ "
-		} else {
-			projectPrefix = "  ├╼ "
+		indent := ""
+		if len(teamIDs) > 1 {
+			indent = "  "
+			contentStrings = append(contentStrings, valueStyle.Render("  "+teamLabel(teamID, teamProjects[0].TeamName)))
+		}
+
+		for i, project := range teamProjects {
+			// Determine if this is the last project
+			isLastProject := (i == len(teamProjects)-1)
+			var projectPrefix string
+
+			if isLastProject {
+				projectPrefix = indent + "  └╼ "
+			} else {
+				projectPrefix = indent + "  ├╼ "
+			}
+
+			// Display project name and ID: "├╼ {name} (id)"
+			projectLine := valueStyle.Render(projectPrefix+project.Name) + " " + idStyle.Render("("+project.ID+")")
+			contentStrings = append(contentStrings, projectLine)
+		}
+	}
+
+	// Display watched files
+	if len(m.previewProfile.Files) > 0 {
+		contentStrings = append(contentStrings, "")
+		contentStrings = append(contentStrings, labelStyle.Render("  Files:"))
+		for i, file := range m.previewProfile.Files {
+			filePrefix := "  ├╼ "
+			if i == len(m.previewProfile.Files)-1 {
+				filePrefix = "  └╼ "
+			}
+			fileLine := valueStyle.Render(filePrefix+file.Name) + " " + idStyle.Render("("+file.Key+")")
+			contentStrings = append(contentStrings, fileLine)
+		}
+	}
+
+	// Display file name rules
+	if len(m.previewProfile.Include) > 0 {
+		contentStrings = append(contentStrings, "")
+		contentStrings = append(contentStrings, labelStyle.Render("  Include: ")+valueStyle.Render(strings.Join(m.previewProfile.Include, ", ")))
+	}
+	if len(m.previewProfile.Exclude) > 0 {
+		if len(m.previewProfile.Include) == 0 {
+			contentStrings = append(contentStrings, "")
 		}
+		contentStrings = append(contentStrings, labelStyle.Render("  Exclude: ")+valueStyle.Render(strings.Join(m.previewProfile.Exclude, ", ")))
+	}
 
-		// Display project name and ID: "├╼ {name} (id)"
-		projectLine := valueStyle.Render(projectPrefix+project.Name) + " " + idStyle.Render("("+project.ID+")")
-		contentStrings = append(contentStrings, projectLine)
+	// Display the sprint cadence
+	if sprint := m.previewProfile.Sprint; sprint != nil {
+		contentStrings = append(contentStrings, "")
+		contentStrings = append(contentStrings, labelStyle.Render("  Sprint: ")+valueStyle.Render(fmt.Sprintf("%d days, starting %s", sprint.Length, sprint.Start)))
+	}
+	if m.previewProfile.Timezone != "" {
+		if m.previewProfile.Sprint == nil {
+			contentStrings = append(contentStrings, "")
+		}
+		contentStrings = append(contentStrings, labelStyle.Render("  Timezone: ")+valueStyle.Render(m.previewProfile.Timezone))
 	}
 
 	contentStrings = append(contentStrings, "")
@@ -2394,7 +3325,16 @@ func (m model) viewProfilePreview() string {
 }
 
 func resolveTimeWindow(config ReportConfig) TimeWindow {
-	now := time.Now()
+	return windowAt(config, time.Now())
+}
+
+// windowAt resolves a report configuration as of now, counting days in the configuration's zone
+func windowAt(config ReportConfig, now time.Time) TimeWindow {
+	location := config.Location
+	if location == nil {
+		location = time.Local
+	}
+	now = now.In(location)
 	var start, end time.Time
 
 	switch config.TimeMode {
@@ -2408,7 +3348,7 @@ func resolveTimeWindow(config ReportConfig) TimeWindow {
 		firstOfThisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
 		firstOfLastMonth := firstOfThisMonth.AddDate(0, -1, 0)
 		start = firstOfLastMonth
-		end = firstOfThisMonth.Add(-time.Second) // Last second of previous month
+		end = firstOfThisMonth // Windows end before End, so this is the whole previous month
 
 	case timeModeThisMonthToDate:
 		// From first day of current month to now
@@ -2424,223 +3364,356 @@ func resolveTimeWindow(config ReportConfig) TimeWindow {
 		// Last 30 days
 		start = now.AddDate(0, 0, -30)
 		end = now
-	}
-
-	return TimeWindow{
-		Start: start,
-		End:   end,
-	}
-}
-
-func generateReport(token, userID, userHandle, teamID string, config ReportConfig, profile *Profile) tea.Cmd {
-	return func() tea.Msg {
-		window := resolveTimeWindow(config)
-
-		// Ensure profile is selected
-		if profile == nil {
-			return reportErrMsg{err: "No profile selected. Please select a profile or create one in Manage Profiles."}
-		}
-
-		client := &http.Client{Timeout: 30 * time.Second}
-
-		// Fetch all files from profile's selected projects
-		var files []FileActivity
-		for _, project := range profile.SelectedProjects {
-			// Fetch files for this project
-			url := fmt.Sprintf("https://api.figma.com/v1/projects/%s/files", project.ID)
-			req, err := http.NewRequest("GET", url, nil)
-			if err != nil {
-				continue
-			}
-			req.Header.Set("X-Figma-Token", token)
 
-			resp, err := client.Do(req)
-			if err != nil {
-				continue
-			}
+	case timeModePrevWeek:
+		// Previous ISO week, Monday to Sunday
+		thisWeek := startOfISOWeek(now)
+		start = thisWeek.AddDate(0, 0, -7)
+		end = thisWeek
 
-			if resp.StatusCode != 200 {
-				resp.Body.Close()
-				continue
-			}
+	case timeModeThisWeek:
+		// From Monday to now
+		start = startOfISOWeek(now)
+		end = now
 
-			body, _ := io.ReadAll(resp.Body)
-			resp.Body.Close()
+	case timeModePrevQuarter:
+		// Previous calendar quarter
+		thisQuarter := startOfQuarter(now)
+		start = thisQuarter.AddDate(0, -3, 0)
+		end = thisQuarter
 
-			var projectFilesResp struct {
-				Files []struct {
-					Key  string `json:"key"`
-					Name string `json:"name"`
-				} `json:"files"`
-			}
-			if err := json.Unmarshal(body, &projectFilesResp); err != nil {
-				continue
-			}
+	case timeModeThisQuarter:
+		// From the first day of the current quarter to now
+		start = startOfQuarter(now)
+		end = now
 
-			// For each file, check if user modified it in time window
-			for _, fileInfo := range projectFilesResp.Files {
-				// Get file metadata
-				fileURL := fmt.Sprintf("https://api.figma.com/v1/files/%s", fileInfo.Key)
-				fileReq, err := http.NewRequest("GET", fileURL, nil)
-				if err != nil {
-					continue
-				}
-				fileReq.Header.Set("X-Figma-Token", token)
+	case timeModeYearToDate:
+		// From January 1st to now
+		start = time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+		end = now
 
-				fileResp, err := client.Do(fileReq)
-				if err != nil {
-					continue
-				}
+	case timeModePrevYear:
+		// Previous calendar year
+		thisYear := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+		start = thisYear.AddDate(-1, 0, 0)
+		end = thisYear
+
+	case timeModeSprint:
+		// Checked with validate when the timeframe was chosen
+		start, end = config.Sprint.window(now, config.SprintsBack)
+
+	case timeModeSinceLast:
+		// From where the profile's last since-last report ended; a first run covers the last week
+		start = now.AddDate(0, 0, -firstRunDays)
+		if !config.From.IsZero() {
+			start = config.From.In(location)
+		}
+		end = now
 
-				if fileResp.StatusCode != 200 {
-					fileResp.Body.Close()
-					continue
-				}
+	case timeModeCustom:
+		// Resolved when the range was parsed, in the same zone
+		start = config.From.In(location)
+		end = config.To.In(location)
+	}
 
-				fileBody, _ := io.ReadAll(fileResp.Body)
-				fileResp.Body.Close()
+	return TimeWindow{
+		Start: start,
+		End:   end,
+		Zone:  zoneName(location, now),
+	}
+}
 
-				var fileData struct {
-					Name         string    `json:"name"`
-					LastModified time.Time `json:"lastModified"`
-					Version      string    `json:"version"`
-				}
-				json.Unmarshal(fileBody, &fileData)
-
-				// Get file version history to determine created date
-				versionsURL := fmt.Sprintf("https://api.figma.com/v1/files/%s/versions", fileInfo.Key)
-				versionsReq, err := http.NewRequest("GET", versionsURL, nil)
-				if err == nil {
-					versionsReq.Header.Set("X-Figma-Token", token)
-					versionsResp, err := client.Do(versionsReq)
-					if err == nil && versionsResp.StatusCode == 200 {
-						versionsBody, _ := io.ReadAll(versionsResp.Body)
-						versionsResp.Body.Close()
-
-						var versionsData struct {
-							Versions []struct {
-								CreatedAt time.Time `json:"created_at"`
-							} `json:"versions"`
-						}
-						json.Unmarshal(versionsBody, &versionsData)
+// generateReport runs a scan and relays its progress. Cancelling ctx ends the scan early with a partial report.
+func generateReport(ctx context.Context, run int, client *FigmaClient, userID, userHandle string, config ReportConfig, profile *Profile, opts scanOptions, fixtures fixtureOptions) tea.Cmd {
+	// Only the latest progress matters, so a slow UI drops intermediate updates instead of blocking workers
+	updates := make(chan scanProgress, 1)
+	opts.Progress = func(progress scanProgress) {
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- progress
+	}
 
-						// Get earliest version (file creation date)
-						var createdAt time.Time
-						if len(versionsData.Versions) > 0 {
-							createdAt = versionsData.Versions[len(versionsData.Versions)-1].CreatedAt
-						}
+	scan := func() tea.Msg {
+		defer close(updates)
 
-						// Check if file was created in the time window
-						createdInWindow := false
-						if !createdAt.IsZero() && createdAt.After(window.Start) && createdAt.Before(window.End) {
-							createdInWindow = true
-						}
+		window := resolveTimeWindow(config)
 
-						// Check if file was modified in the time window
-						myChanges := false
-						if fileData.LastModified.After(window.Start) && fileData.LastModified.Before(window.End) {
-							myChanges = true
-						}
+		// A replay covers the recorded window, whichever time option was picked
+		recorded, err := fixtures.recordedRun()
+		if err != nil {
+			return reportErrMsg{run: run, err: err.Error()}
+		}
+		if recorded != nil {
+			window = recorded.Window
+		}
 
-						// Only include files with activity (created or modified in window)
-						if myChanges || createdInWindow {
-							files = append(files, FileActivity{
-								FileKey:         fileInfo.Key,
-								FileName:        fileData.Name,
-								ProjectName:     project.Name, // Use project name from profile
-								LastModified:    fileData.LastModified,
-								CreatedAt:       createdAt,
-								MyChanges:       myChanges,
-								CreatedInWindow: createdInWindow,
-								Versions:        []FigmaVersion{},
-								Comments:        []FigmaComment{},
-							})
-						}
-					}
-				}
-			}
+		// Ensure profile is selected
+		if profile == nil {
+			return reportErrMsg{run: run, err: "No profile selected. Please select a profile or create one in Manage Profiles."}
 		}
 
-		// Build report
-		report := &ActivityReport{
-			TimeWindow:   window,
-			UserID:       userID,
-			UserHandle:   userHandle,
-			Files:        files,
-			TotalFiles:   len(files),
-			TotalChanges: 0,
-			GeneratedAt:  time.Now(),
+		filter, err := newFileFilter(profile.Include, profile.Exclude)
+		if err != nil {
+			return reportErrMsg{run: run, err: "Profile has an invalid file rule: " + err.Error()}
 		}
+		opts.Filter = filter
 
-		// Count total changes
-		for _, file := range files {
-			if file.MyChanges {
-				report.TotalChanges++
-			}
+		report := scanActivity(ctx, client, profile.withFiles(config.FileKeys), window, userID, userHandle, opts)
+		if recorded != nil {
+			report.GeneratedAt = recorded.GeneratedAt
 		}
 
 		// Format report content
 		content := formatReportMarkdown(report)
+		if err := fixtures.recordRun(report); err != nil {
+			content += fmt.Sprintf("\n_%s_\n", err)
+		}
+		if fixtures.ReplayDir == "" {
+			if err := advanceLastRun(profile.Name, config, report); err != nil {
+				content += fmt.Sprintf("\n_This run could not be recorded for \"Since Last Run\": %s_\n", err)
+			}
+		}
 
 		return reportGeneratedMsg{
+			run:     run,
 			report:  report,
 			content: content,
 		}
 	}
+
+	return tea.Batch(scan, waitForReportProgress(run, updates))
+}
+
+// waitForReportProgress delivers the next progress update of a scan, or nothing once it has finished
+func waitForReportProgress(run int, updates <-chan scanProgress) tea.Cmd {
+	return func() tea.Msg {
+		progress, ok := <-updates
+		if !ok {
+			return nil
+		}
+		return reportProgressMsg{run: run, progress: progress, updates: updates}
+	}
 }
 
 func formatReportMarkdown(report *ActivityReport) string {
 	var sb strings.Builder
 
 	sb.WriteString("# Status Report\n")
-	sb.WriteString(fmt.Sprintf("## From %s to %s\n",
+	sb.WriteString(fmt.Sprintf("## From %s to %s (%s)\n",
 		report.TimeWindow.Start.Format("2006-01-02"),
-		report.TimeWindow.End.Format("2006-01-02")))
+		report.TimeWindow.lastDay(),
+		report.TimeWindow.Zone))
 
 	// Add user information if available
 	if report.UserHandle != "" {
-		sb.WriteString(fmt.Sprintf("User: %s\n\n", report.UserHandle))
-	} else {
-		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf("User: %s\n", report.UserHandle))
+	}
+	if report.Teammates {
+		sb.WriteString("Including files changed only by teammates\n")
+	}
+	if report.MyComments {
+		sb.WriteString("Showing only comments by or mentioning the user\n")
 	}
+	if report.Cancelled {
+		sb.WriteString(fmt.Sprintf("**Scan cancelled**: partial report, %d listed file(s) not scanned\n", report.Unscanned))
+	}
+	sb.WriteString("\n")
 
 	if len(report.Files) == 0 {
 		sb.WriteString("No file activity found in the selected time period.\n")
 	} else {
-		// Group by project
-		projectFiles := make(map[string][]FileActivity)
+		// Group by team, then project, keeping both in the order they were scanned
+		type projectKey struct{ team, project string }
+		projectFiles := make(map[projectKey][]FileActivity)
+		var projectOrder []projectKey
+		teamNames := make(map[string]string)
+		var teamOrder []string
 		for _, file := range report.Files {
 			projectName := file.ProjectName
 			if projectName == "" {
 				projectName = "Unknown Project"
 			}
-			projectFiles[projectName] = append(projectFiles[projectName], file)
+			key := projectKey{team: file.TeamID, project: projectName}
+			if _, seen := projectFiles[key]; !seen {
+				projectOrder = append(projectOrder, key)
+			}
+			projectFiles[key] = append(projectFiles[key], file)
+			if _, seen := teamNames[file.TeamID]; !seen {
+				teamOrder = append(teamOrder, file.TeamID)
+			}
+			teamNames[file.TeamID] = file.TeamName
 		}
 
-		for projectName, files := range projectFiles {
-			sb.WriteString(fmt.Sprintf("\n### %s\n\n", projectName))
-			for _, file := range files {
-				// Determine status
-				status := "Modified"
-				if file.CreatedInWindow {
-					status = "Created"
+		// Team headings only matter when the profile spans several teams
+		var orderedProjects []projectKey
+		for _, team := range teamOrder {
+			for _, key := range projectOrder {
+				if key.team == team {
+					orderedProjects = append(orderedProjects, key)
 				}
+			}
+		}
+
+		for i, key := range orderedProjects {
+			if len(teamOrder) > 1 && (i == 0 || key.team != orderedProjects[i-1].team) {
+				sb.WriteString(fmt.Sprintf("\n## %s\n", teamLabel(key.team, teamNames[key.team])))
+			}
+			files := projectFiles[key]
+			sb.WriteString(fmt.Sprintf("\n### %s\n\n", key.project))
+			for _, file := range files {
+				writeFileMarkdown(&sb, file)
+			}
+		}
+	}
 
-				// Create Figma file URL
-				figmaURL := fmt.Sprintf("https://www.figma.com/file/%s", file.FileKey)
+	writeTimelineMarkdown(&sb, report)
 
-				// Format: - File name, link (Created/Modified)
-				sb.WriteString(fmt.Sprintf("- [%s](%s) (%s)\n",
-					file.FileName,
-					figmaURL,
-					status))
+	// List what could not be scanned so it is not mistaken for inactivity
+	if len(report.Warnings) > 0 {
+		sb.WriteString(fmt.Sprintf("\n### Warnings (%d)\n\n", len(report.Warnings)))
+		sb.WriteString("This report is incomplete.\n\n")
+		for _, warning := range report.Warnings {
+			if warning.Scope == "project" {
+				sb.WriteString(fmt.Sprintf("- Project %s: %s\n", warning.ProjectName, warning.Message))
+			} else {
+				sb.WriteString(fmt.Sprintf("- %s (%s): %s\n", warning.FileName, warning.ProjectName, warning.Message))
 			}
 		}
 	}
 
+	// Say how many files the rules hid so a filtered report isn't mistaken for a complete one
+	if report.FilteredOut > 0 {
+		sb.WriteString(fmt.Sprintf("\n---\n%d file(s) filtered out by include/exclude rules\n", report.FilteredOut))
+	}
+
 	return sb.String()
 }
 
+// writeFileMarkdown writes a file's list item, with its branches nested below it
+func writeFileMarkdown(sb *strings.Builder, file FileActivity) {
+	// Determine status
+	status := "Modified"
+	if file.CreatedInWindow {
+		status = "Created"
+	}
+	if !file.MyChanges && !file.CreatedInWindow && !file.TeammateChanges && len(file.Comments) > 0 {
+		status = "Commented"
+	}
+	if !file.MyChanges && !file.CreatedInWindow && !file.TeammateChanges && file.UnattributedChanges {
+		// Could be the user's own work, so it isn't put down to teammates
+		status = "Modified, no version saved yet"
+	}
+	if !file.MyChanges && file.TeammateChanges {
+		// Teammate-only files are labelled so they aren't read as the user's own work
+		status += " by teammates"
+		if len(file.Teammates) > 0 {
+			status += ": " + strings.Join(file.Teammates, ", ")
+		}
+	}
+	// A main file listed only to hold its branches
+	if !file.MyChanges && !file.CreatedInWindow && !file.TeammateChanges && len(file.Comments) == 0 && len(file.Branches) > 0 {
+		status = "No changes on main"
+	}
+
+	// Create Figma file URL
+	figmaURL := fmt.Sprintf("https://www.figma.com/file/%s", file.FileKey)
+	prefix := ""
+	if file.MainFileKey != "" {
+		figmaURL = fmt.Sprintf("https://www.figma.com/file/%s/branch/%s", file.MainFileKey, file.FileKey)
+		prefix = "Branch "
+		if file.BranchStatus != "" {
+			status += ", " + file.BranchStatus
+		}
+	}
+
+	// Format: - File name, link (Created/Modified)
+	sb.WriteString(fmt.Sprintf("- %s[%s](%s) (%s)\n",
+		prefix,
+		file.FileName,
+		figmaURL,
+		status))
+
+	writeMilestonesMarkdown(sb, file.Versions)
+	writeCommentsMarkdown(sb, file.Comments)
+
+	// Branches are written like files, then indented one level
+	for _, branch := range file.Branches {
+		var branchSB strings.Builder
+		writeFileMarkdown(&branchSB, branch)
+		for _, line := range strings.SplitAfter(branchSB.String(), "\n") {
+			if line != "" {
+				sb.WriteString("  " + line)
+			}
+		}
+	}
+}
+
+// writeMilestonesMarkdown lists the named versions saved in the window with their release notes
+func writeMilestonesMarkdown(sb *strings.Builder, versions []FigmaVersion) {
+	var milestones []FigmaVersion
+	for _, version := range versions {
+		if strings.TrimSpace(version.Label) != "" {
+			milestones = append(milestones, version)
+		}
+	}
+	if len(milestones) == 0 {
+		return
+	}
+
+	sb.WriteString("  - Milestones\n")
+	for _, version := range milestones {
+		sb.WriteString(fmt.Sprintf("    - **%s** (%s)", strings.TrimSpace(version.Label), version.Created.Format("2006-01-02")))
+		description := strings.TrimSpace(version.Description)
+		if description == "" {
+			sb.WriteString("\n")
+			continue
+		}
+		// Keep the designer's line breaks, indented so they stay inside the list item
+		lines := strings.Split(description, "\n")
+		sb.WriteString(": " + strings.TrimSpace(lines[0]) + "\n")
+		for _, line := range lines[1:] {
+			if line = strings.TrimSpace(line); line != "" {
+				sb.WriteString("      " + line + "\n")
+			}
+		}
+	}
+}
+
+// Comment excerpts shown per file in Markdown reports
+const (
+	maxCommentExcerpts   = 3
+	commentExcerptLength = 80
+)
+
+func writeCommentsMarkdown(sb *strings.Builder, comments []FigmaComment) {
+	if len(comments) == 0 {
+		return
+	}
+
+	noun := "comments"
+	if len(comments) == 1 {
+		noun = "comment"
+	}
+	sb.WriteString(fmt.Sprintf("  - %d %s\n", len(comments), noun))
+
+	// Most recent comments first
+	shown := 0
+	for i := len(comments) - 1; i >= 0 && shown < maxCommentExcerpts; i-- {
+		comment := comments[i]
+		excerpt := strings.Join(strings.Fields(comment.Message), " ")
+		if runes := []rune(excerpt); len(runes) > commentExcerptLength {
+			excerpt = string(runes[:commentExcerptLength]) + "…"
+		}
+		sb.WriteString(fmt.Sprintf("    - %s: \"%s\"\n", comment.User.Handle, excerpt))
+		shown++
+	}
+	if len(comments) > shown {
+		sb.WriteString(fmt.Sprintf("    - …and %d more\n", len(comments)-shown))
+	}
+}
+
 func exportReport(content string, profileName string) tea.Cmd {
 	return func() tea.Msg {
 		// Create reports directory in current working directory
@@ -2693,14 +3766,47 @@ func (m model) viewReportView() string {
 	contentStrings = append(contentStrings, "")
 
 	if m.generatingReport {
-		contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(whiteColor).Bold(true).Render("  Generating Report..."))
-		contentStrings = append(contentStrings, "")
-		contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("  Please wait while we fetch your Figma activity data..."))
+		if m.cancellingReport {
+			contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(whiteColor).Bold(true).Render("  Stopping scan..."))
+			contentStrings = append(contentStrings, "")
+			contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("  Waiting for requests in flight, then showing the partial report"))
+		} else {
+			contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(whiteColor).Bold(true).Render("  Generating Report..."))
+			contentStrings = append(contentStrings, "")
+			contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("  Please wait while we fetch your Figma activity data..."))
+		}
+		if m.reportProgress.Projects > 0 {
+			contentStrings = append(contentStrings, "")
+			contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(defaultTextColor).Render("  "+m.reportProgress.String()))
+		}
 	} else if m.reportError != "" {
 		contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(lipgloss.Color("#ea4536")).Bold(true).Render("  Error"))
 		contentStrings = append(contentStrings, "")
 		contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(defaultTextColor).Render("  "+m.reportError))
 	} else if m.reportContent != "" {
+		// Flag partial reports before the content so they aren't read as complete
+		if m.activityReport != nil && m.activityReport.Cancelled {
+			cancelledText := fmt.Sprintf("  ⏹ Scan cancelled: partial report, %d listed file(s) not scanned.", m.activityReport.Unscanned)
+			contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(lipgloss.Color("#ed7139")).Bold(true).Render(cancelledText))
+		}
+		if m.activityReport != nil && len(m.activityReport.Warnings) > 0 {
+			warningText := fmt.Sprintf("  ⚠ %d warning(s): some projects or files could not be scanned. See Warnings at the end of the report.", len(m.activityReport.Warnings))
+			contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(lipgloss.Color("#ed7139")).Bold(true).Render(warningText))
+		}
+
+		// Activity over the window at a glance; the table is in the report below
+		if m.activityReport != nil {
+			if busiest, found := busiestBucket(m.activityReport.Timeline); found {
+				unit := "day"
+				if timelineWeekly(m.activityReport.TimeWindow) {
+					unit = "week"
+				}
+				sparkText := lipgloss.NewStyle().Foreground(cyanColor).Render(sparkline(m.activityReport.Timeline))
+				busiestText := fmt.Sprintf("busiest %s: %s, %d event(s)", unit, timelineLabel(busiest, unit == "week"), busiest.events())
+				contentStrings = append(contentStrings, "  "+lipgloss.NewStyle().Foreground(dimWhiteColor).Render("Activity ")+sparkText+"  "+lipgloss.NewStyle().Foreground(dimWhiteColor).Render(busiestText))
+			}
+		}
+
 		// Render markdown using glamour
 		r, err := glamour.NewTermRenderer(
 			glamour.WithAutoStyle(),
@@ -2740,8 +3846,12 @@ func (m model) viewReportView() string {
 
 
 	// Footer
+	escText := "back to menu"
+	if m.generatingReport && !m.cancellingReport {
+		escText = "stop scan"
+	}
 	escStyle := lipgloss.NewStyle().Foreground(cyanColor).Render("esc")
-	escDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("back to menu")
+	escDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render(escText)
 	leftShortcuts := lipgloss.JoinHorizontal(lipgloss.Top, escStyle, " ", escDesc)
 
 	dots := ""
@@ -2770,6 +3880,7 @@ func (m model) viewReportConfig() string {
 	defaultTextColor := lipgloss.Color("#C5C5C5")
 	cyanColor := lipgloss.Color("#00c7ff")
 	dimWhiteColor := lipgloss.Color("rgba(255,255,255,0.4)")
+	grayColor := lipgloss.Color("#7c7c7c")
 	statusBgColor := lipgloss.Color("rgba(0,0,0,0.27)")
 
 	// Gradient colors for header and divider
@@ -2861,6 +3972,58 @@ func (m model) viewReportConfig() string {
 		contentStrings = append(contentStrings, optionStyle.Render(prefix+option))
 	}
 
+	// Custom range form, shown while its option is selected
+	if m.reportTimeIndex == len(m.reportTimeOptions)-1 {
+		contentStrings = append(contentStrings, "")
+		fields := []struct {
+			label string
+			value string
+			empty string
+		}{
+			{"      From: ", m.reportCustomFrom, "Not set"},
+			{"      To:   ", m.reportCustomTo, "now"},
+		}
+		for i, field := range fields {
+			line := lipgloss.NewStyle().Foreground(dimWhiteColor).Render(field.label)
+			if m.reportCustomField == i {
+				inputStyle := lipgloss.NewStyle().
+					Background(grayColor).
+					Foreground(whiteColor)
+				line += inputStyle.Render(m.textInput.View())
+			} else if field.value == "" {
+				line += lipgloss.NewStyle().Foreground(dimWhiteColor).Render(field.empty)
+			} else {
+				line += lipgloss.NewStyle().Foreground(defaultTextColor).Render(field.value)
+			}
+			contentStrings = append(contentStrings, line)
+		}
+		contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("      Dates (2026-09-01), weeks (2026-w37), months (2026-09), quarters (2026-q3), today or last-monday"))
+	}
+
+	// Where a since-last report would start for the selected profile
+	if m.reportLastRun != "" {
+		contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("      "+m.reportLastRun))
+	}
+	if m.reportTimeError != "" {
+		contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(lipgloss.Color("#ea4536")).Render("      Error: "+m.reportTimeError))
+	}
+
+	contentStrings = append(contentStrings, "")
+
+	// Teammate activity toggle
+	teammatesValue := "off"
+	if m.reportTeammates {
+		teammatesValue = "on"
+	}
+	contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("  Include teammate-only files: ")+lipgloss.NewStyle().Foreground(defaultTextColor).Render(teammatesValue))
+
+	// Comment filter toggle
+	commentsValue := "all"
+	if m.reportMyComments {
+		commentsValue = "by or mentioning me"
+	}
+	contentStrings = append(contentStrings, lipgloss.NewStyle().Foreground(dimWhiteColor).Render("  Comments: ")+lipgloss.NewStyle().Foreground(defaultTextColor).Render(commentsValue))
+
 	contentStrings = append(contentStrings, "")
 	contentStrings = append(contentStrings, "")
 
@@ -2877,11 +4040,28 @@ func (m model) viewReportConfig() string {
 	enterDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("generate")
 	arrowsStyle := lipgloss.NewStyle().Foreground(cyanColor).Render("←/→")
 	arrowsDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("profile")
+	teammatesStyle := lipgloss.NewStyle().Foreground(cyanColor).Render("t")
+	teammatesDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("teammates")
+	commentsStyle := lipgloss.NewStyle().Foreground(cyanColor).Render("c")
+	commentsDesc := lipgloss.NewStyle().Foreground(dimWhiteColor).Render("comments")
 
 	leftShortcuts := lipgloss.JoinHorizontal(lipgloss.Top,
 		escStyle, " ", escDesc, "    ",
 		arrowsStyle, " ", arrowsDesc, "    ",
+		teammatesStyle, " ", teammatesDesc, "    ",
+		commentsStyle, " ", commentsDesc, "    ",
 		enterStyle, " ", enterDesc)
+	if m.reportCustomField >= 0 {
+		// Editing the custom range
+		escDesc = lipgloss.NewStyle().Foreground(dimWhiteColor).Render("close")
+		enterDesc = lipgloss.NewStyle().Foreground(dimWhiteColor).Render("next")
+		if m.reportCustomField == 1 {
+			enterDesc = lipgloss.NewStyle().Foreground(dimWhiteColor).Render("generate")
+		}
+		leftShortcuts = lipgloss.JoinHorizontal(lipgloss.Top,
+			escStyle, " ", escDesc, "    ",
+			enterStyle, " ", enterDesc)
+	}
 
 	dots := ""
 	for _, color := range gradientColors {
@@ -3123,26 +4303,78 @@ func createGradientDivider(width int, colors []lipgloss.Color) string {
 	return result
 }
 
-func runCLI(profileName, timeframe, projectsStr, userID, format string, saveReport bool) error {
-	// Load configuration
-	cfg, err := loadConfig()
+// errPartialScan means the report was written but some projects or files could not be read
+var errPartialScan = errors.New("scan incomplete")
+
+// Exit codes for CLI mode
+const (
+	exitError       = 1
+	exitPartialScan = 3 // report printed, but with warnings
+)
+
+// cliOptions holds the command-line flags for a headless run
+type cliOptions struct {
+	Profile     string
+	Timeframe   string
+	From        string // custom range start; takes precedence over Timeframe
+	To          string // custom range end, empty for now
+	NoAdvance   bool   // -t since-last without moving the profile's last-run marker
+	Timezone    string // zone report windows are counted in, overrides profile and config
+	Projects    string // comma-separated project IDs
+	Users       string // comma-separated user IDs
+	Format      string
+	SaveReport  bool
+	Concurrency int
+	Teammates   bool
+	MyComments  bool
+	NoCache     bool
+	Fixtures    fixtureOptions
+	Context     string   // named account to use instead of the current context
+	Files       string   // comma-separated file keys or URLs, scanned directly
+	Include     []string // file name rules replacing the profile's include rules
+	Exclude     []string // file name rules replacing the profile's exclude rules
+}
+
+func runCLI(opts cliOptions) error {
+	profileName := opts.Profile
+	projectsStr := opts.Projects
+	format := opts.Format
+
+	// Load configuration, with environment overrides
+	cfg, err := resolveConfig(opts.Context)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
-	if cfg.FigmaToken == "" {
-		return fmt.Errorf("Figma token not configured. Run setup first or use the TUI")
+	// Replays don't need a token; the recording never stored one
+	if cfg.FigmaToken == "" && cfg.OAuth == nil && opts.Fixtures.ReplayDir == "" {
+		return fmt.Errorf("Figma token not configured. Run setup first, use the TUI or run 'figma-beacon login'")
+	}
+
+	client := NewFigmaClient(cfg.FigmaToken, cfg.apiSettings)
+	authorize(client, cfg.config, cfg.context)
+	if !opts.NoCache {
+		client.Cache, _ = openResponseCache()
 	}
+	opts.Fixtures.apply(client)
 
 	// Determine profile to use
+	// Files given with -files are checked directly, on top of the profile or -proj
+	fileKeys, err := parseFileKeys(opts.Files)
+	if err != nil {
+		return err
+	}
+
 	var profile *Profile
-	if projectsStr == "" && userID == "" {
+	savedProfile := projectsStr == "" && (len(fileKeys) == 0 || profileName != "")
+	if savedProfile {
 		// Load from profile
 		if profileName == "" {
 			profileName = "default"
 		}
 
-		profiles, err := loadAllProfiles()
+		// Only the active context's profiles; they were set up for its account
+		profiles, err := loadContextProfiles(cfg.context)
 		if err != nil {
 			return fmt.Errorf("failed to load profiles: %w", err)
 		}
@@ -3162,28 +4394,30 @@ func runCLI(profileName, timeframe, projectsStr, userID, format string, saveRepo
 			if profileName == "default" {
 				return fmt.Errorf("no default profile found. Create a profile using the TUI or specify -proj and -u flags")
 			}
+			if cfg.context != defaultContextName {
+				return fmt.Errorf("profile '%s' not found in context '%s'", profileName, cfg.context)
+			}
 			return fmt.Errorf("profile '%s' not found", profileName)
 		}
+	} else if projectsStr == "" {
+		// Only -files: report on just those files
+		profile = &Profile{
+			Name:   "cli-override",
+			TeamID: cfg.TeamID,
+		}
 	} else {
 		// Override with CLI flags
-		if projectsStr != "" && userID == "" {
+		if opts.Users == "" {
 			fmt.Fprintf(os.Stderr, "Warning: -proj specified without -u. Using user from config.\n")
 		}
-		if userID != "" && projectsStr == "" {
-			return fmt.Errorf("-u flag requires -proj flag to specify which projects to scan")
-		}
 
 		// Parse project IDs
-		projectIDs := strings.Split(projectsStr, ",")
 		var projects []ProfileProject
-		for _, id := range projectIDs {
-			id = strings.TrimSpace(id)
-			if id != "" {
-				projects = append(projects, ProfileProject{
-					ID:   id,
-					Name: id, // We don't have the name, use ID
-				})
-			}
+		for _, id := range splitList(projectsStr) {
+			projects = append(projects, ProfileProject{
+				ID:   id,
+				Name: id, // We don't have the name, use ID
+			})
 		}
 
 		profile = &Profile{
@@ -3192,188 +4426,108 @@ func runCLI(profileName, timeframe, projectsStr, userID, format string, saveRepo
 			SelectedProjects: projects,
 		}
 
-		// Override user if provided
-		if userID != "" {
-			cfg.UserID = userID
-			// Try to fetch user handle
-			client := &http.Client{Timeout: 10 * time.Second}
-			req, _ := http.NewRequest("GET", "https://api.figma.com/v1/me", nil)
-			req.Header.Set("X-Figma-Token", cfg.FigmaToken)
-			resp, err := client.Do(req)
-			if err == nil && resp.StatusCode == 200 {
-				body, _ := io.ReadAll(resp.Body)
-				resp.Body.Close()
-				var result struct {
-					Handle string `json:"handle"`
-				}
-				if json.Unmarshal(body, &result) == nil {
-					cfg.UserHandle = result.Handle
-				}
-			}
-		}
-
-		if projectsStr != "" {
-			fmt.Fprintf(os.Stderr, "Warning: Using -proj and -u flags overrides profile settings.\n")
-		}
+		fmt.Fprintf(os.Stderr, "Warning: Using -proj and -u flags overrides profile settings.\n")
 	}
 
-	// Parse timeframe
-	var timeMode timeMode
-	switch strings.ToLower(timeframe) {
-	case "week", "7d":
-		timeMode = timeModeLastWeek
-	case "month":
-		timeMode = timeModeLastMonth
-	case "m2d", "mtd":
-		timeMode = timeModeThisMonthToDate
-	case "4w", "28d":
-		timeMode = timeModeLast4Weeks
-	case "30d":
-		timeMode = timeModeLast30Days
-	default:
-		return fmt.Errorf("invalid timeframe '%s'. Valid options: week, month, m2d, 4w, 30d", timeframe)
+	// Attribute changes to the configured user unless -u lists others
+	var userIDs []string
+	if cfg.UserID != "" {
+		userIDs = []string{cfg.UserID}
 	}
-
-	// Generate report
-	reportConfig := ReportConfig{
-		TimeMode: timeMode,
+	if opts.Users != "" {
+		userIDs = splitList(opts.Users)
+		cfg.UserID = strings.Join(userIDs, ",")
+		cfg.UserHandle = ""
+	}
+	// Users from -u or FIGMA_USER_ID have no stored handle; show one when reporting on the token owner
+	if cfg.UserHandle == "" && len(userIDs) > 0 {
+		cfg.UserHandle = cfg.UserID
+		if user, err := client.Me(context.Background()); err == nil && len(userIDs) == 1 && userIDs[0] == user.ID {
+			cfg.UserHandle = user.Handle
+		}
 	}
 
-	window := resolveTimeWindow(reportConfig)
-
-	// Fetch activity
-	client := &http.Client{Timeout: 30 * time.Second}
-	var files []FileActivity
+	// Days are counted in the report's zone, so a UTC runner agrees with the team's calendar
+	location, err := reportLocation(opts.Timezone, profile, cfg.Timezone)
+	if err != nil {
+		return err
+	}
+	now := time.Now().In(location)
 
-	for _, project := range profile.SelectedProjects {
-		// Fetch files for this project
-		url := fmt.Sprintf("https://api.figma.com/v1/projects/%s/files", project.ID)
-		req, err := http.NewRequest("GET", url, nil)
+	// Parse timeframe; -from and -to replace -t
+	var reportConfig ReportConfig
+	switch {
+	case opts.From != "":
+		reportConfig, err = customRange(opts.From, opts.To, now)
 		if err != nil {
-			continue
+			return fmt.Errorf("invalid -from/-to range: %w", err)
 		}
-		req.Header.Set("X-Figma-Token", cfg.FigmaToken)
-
-		resp, err := client.Do(req)
+	case opts.To != "":
+		return fmt.Errorf("-to needs -from")
+	default:
+		reportConfig, err = parseTimeframe(opts.Timeframe, now, profile.Sprint)
 		if err != nil {
-			continue
-		}
-
-		if resp.StatusCode != 200 {
-			resp.Body.Close()
-			continue
+			return err
 		}
+	}
+	reportConfig.Location = location
 
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-
-		var projectFilesResp struct {
-			Files []struct {
-				Key  string `json:"key"`
-				Name string `json:"name"`
-			} `json:"files"`
+	// since-last continues from the profile's previous since-last run
+	if reportConfig.TimeMode == timeModeSinceLast {
+		if !savedProfile {
+			return fmt.Errorf("-t since-last needs a saved profile; -proj and -files alone have no last run")
 		}
-		if err := json.Unmarshal(body, &projectFilesResp); err != nil {
-			continue
+		if reportConfig.From, err = loadLastRun(profile.Name); err != nil {
+			return fmt.Errorf("failed to read the last run: %w", err)
 		}
+	}
 
-		// For each file, check if user modified it in time window
-		for _, fileInfo := range projectFilesResp.Files {
-			// Get file metadata
-			fileURL := fmt.Sprintf("https://api.figma.com/v1/files/%s", fileInfo.Key)
-			fileReq, err := http.NewRequest("GET", fileURL, nil)
-			if err != nil {
-				continue
-			}
-			fileReq.Header.Set("X-Figma-Token", cfg.FigmaToken)
-
-			fileResp, err := client.Do(fileReq)
-			if err != nil {
-				continue
-			}
-
-			if fileResp.StatusCode != 200 {
-				fileResp.Body.Close()
-				continue
-			}
-
-			fileBody, _ := io.ReadAll(fileResp.Body)
-			fileResp.Body.Close()
-
-			var fileData struct {
-				Name         string    `json:"name"`
-				LastModified time.Time `json:"lastModified"`
-			}
-			json.Unmarshal(fileBody, &fileData)
-
-			// Get file version history to determine created date
-			versionsURL := fmt.Sprintf("https://api.figma.com/v1/files/%s/versions", fileInfo.Key)
-			versionsReq, err := http.NewRequest("GET", versionsURL, nil)
-			if err == nil {
-				versionsReq.Header.Set("X-Figma-Token", cfg.FigmaToken)
-				versionsResp, err := client.Do(versionsReq)
-				if err == nil && versionsResp.StatusCode == 200 {
-					versionsBody, _ := io.ReadAll(versionsResp.Body)
-					versionsResp.Body.Close()
-
-					var versionsData struct {
-						Versions []struct {
-							CreatedAt time.Time `json:"created_at"`
-						} `json:"versions"`
-					}
-					json.Unmarshal(versionsBody, &versionsData)
-
-					// Get earliest version (file creation date)
-					var createdAt time.Time
-					if len(versionsData.Versions) > 0 {
-						createdAt = versionsData.Versions[len(versionsData.Versions)-1].CreatedAt
-					}
-
-					// Check if file was created in the time window
-					createdInWindow := false
-					if !createdAt.IsZero() && createdAt.After(window.Start) && createdAt.Before(window.End) {
-						createdInWindow = true
-					}
+	// Generate report
+	reportConfig.FileKeys = fileKeys
 
-					// Check if file was modified in the time window
-					myChanges := false
-					if fileData.LastModified.After(window.Start) && fileData.LastModified.Before(window.End) {
-						myChanges = true
-					}
+	window := resolveTimeWindow(reportConfig)
 
-					// Only include files with activity (created or modified in window)
-					if myChanges || createdInWindow {
-						files = append(files, FileActivity{
-							FileKey:         fileInfo.Key,
-							FileName:        fileData.Name,
-							ProjectName:     project.Name,
-							LastModified:    fileData.LastModified,
-							CreatedAt:       createdAt,
-							MyChanges:       myChanges,
-							CreatedInWindow: createdInWindow,
-						})
-					}
-				}
-			}
-		}
+	// A replay covers the recorded window, whatever day it runs on
+	recorded, err := opts.Fixtures.recordedRun()
+	if err != nil {
+		return err
+	}
+	if recorded != nil {
+		window = recorded.Window
 	}
 
-	// Build report
-	report := &ActivityReport{
-		TimeWindow:   window,
-		UserID:       cfg.UserID,
-		UserHandle:   cfg.UserHandle,
-		Files:        files,
-		TotalFiles:   len(files),
-		TotalChanges: 0,
-		GeneratedAt:  time.Now(),
+	// -include and -exclude replace the profile's rules
+	include, exclude := profile.Include, profile.Exclude
+	if opts.Include != nil {
+		include = opts.Include
+	}
+	if opts.Exclude != nil {
+		exclude = opts.Exclude
+	}
+	filter, err := newFileFilter(include, exclude)
+	if err != nil {
+		return fmt.Errorf("invalid file rule: %w", err)
 	}
 
-	// Count total changes
-	for _, file := range files {
-		if file.MyChanges {
-			report.TotalChanges++
-		}
+	// Fetch activity
+	scanOpts := scanOptions{
+		Concurrency:      resolveConcurrency(opts.Concurrency, profile, cfg.Concurrency),
+		UserIDs:          userIDs,
+		IncludeTeammates: opts.Teammates,
+		UserHandle:       cfg.UserHandle,
+		MyCommentsOnly:   opts.MyComments,
+		Filter:           filter,
+	}
+	// Recordings and replays must not depend on branches remembered from other runs
+	if !opts.Fixtures.active() {
+		scanOpts.Branches, _ = openBranchStore()
+	}
+	report := scanActivity(context.Background(), client, profile.withFiles(reportConfig.FileKeys), window, cfg.UserID, cfg.UserHandle, scanOpts)
+	if recorded != nil {
+		report.GeneratedAt = recorded.GeneratedAt
+	}
+	if err := opts.Fixtures.recordRun(report); err != nil {
+		return err
 	}
 
 	// Format output
@@ -3395,7 +4549,7 @@ func runCLI(profileName, timeframe, projectsStr, userID, format string, saveRepo
 	fmt.Println(output)
 
 	// Save to file if requested
-	if saveReport {
+	if opts.SaveReport {
 		reportsDir := "reports"
 		if err := os.MkdirAll(reportsDir, 0755); err != nil {
 			return fmt.Errorf("failed to create reports directory: %w", err)
@@ -3414,33 +4568,147 @@ func runCLI(profileName, timeframe, projectsStr, userID, format string, saveRepo
 		fmt.Fprintf(os.Stderr, "\nReport saved to: %s\n", fileName)
 	}
 
+	// Only now that the report is out does the next since-last run start where this one ended.
+	// A replay reports on a recorded past run, so it never moves the marker.
+	if !opts.NoAdvance && opts.Fixtures.ReplayDir == "" {
+		if err := advanceLastRun(profile.Name, reportConfig, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record this run: %v\n", err)
+		}
+	}
+
+	if len(report.Warnings) > 0 {
+		return fmt.Errorf("%w: %d problem(s), see Warnings in the report", errPartialScan, len(report.Warnings))
+	}
+
 	return nil
 }
 
+// runCommand handles subcommands such as `figma-beacon cache clear`
+func runCommand(args []string, opts cliOptions) error {
+	switch args[0] {
+	case "config":
+		if len(args) >= 2 && args[1] == "show" {
+			return runConfigShow(args[2:], opts)
+		}
+		return fmt.Errorf("usage: figma-beacon config show [--resolved]")
+	case "context":
+		return runContextCommand(args[1:])
+	case "login":
+		return runLogin(opts.Context)
+	case "logout":
+		return runLogout(opts.Context)
+	case "cache":
+		if len(args) == 2 && args[1] == "clear" {
+			if err := clearCache(); err != nil {
+				return fmt.Errorf("failed to clear cache: %w", err)
+			}
+			fmt.Fprintln(os.Stderr, "Cache cleared")
+			return nil
+		}
+		return fmt.Errorf("usage: figma-beacon cache clear")
+	default:
+		return fmt.Errorf("unknown command '%s'", args[0])
+	}
+}
+
+// Flags that apply to both the TUI and the CLI; setting only these still launches the TUI
+var sharedFlags = map[string]bool{
+	"no-cache": true,
+	"record":   true,
+	"replay":   true,
+	"context":  true,
+}
+
 func main() {
 	// Define CLI flags
 	profileFlag := flag.String("p", "", "Profile name (default: use default profile)")
-	timeframeFlag := flag.String("t", "week", "Timeframe: week, month, m2d, 4w, 30d")
+	timeframeFlag := flag.String("t", "week", "Timeframe: "+timeframeHelp)
+	fromFlag := flag.String("from", "", "Start of a custom range, e.g. 2026-09-01 (replaces -t)")
+	toFlag := flag.String("to", "", "Last day of a custom range (default: now)")
+	noAdvanceFlag := flag.Bool("no-advance", false, "With -t since-last, don't record this run (dry run)")
+	tzFlag := flag.String("tz", "", "Time zone days are counted in, e.g. Europe/Madrid (default: profile or config setting, else the machine's)")
 	projectsFlag := flag.String("proj", "", "Comma-separated project IDs (overrides profile)")
-	userFlag := flag.String("u", "", "User ID (overrides profile)")
+	userFlag := flag.String("u", "", "Comma-separated user IDs whose versions count as changes (default: configured user)")
 	formatFlag := flag.String("format", "md", "Output format: json, md")
 	reportFlag := flag.Bool("report", false, "Save report to file")
+	concurrencyFlag := flag.Int("concurrency", 0, "Files fetched in parallel (default: profile or config setting, else 4)")
+	teammatesFlag := flag.Bool("teammates", false, "Also include files changed only by teammates")
+	myCommentsFlag := flag.Bool("my-comments", false, "Only include comments by or mentioning the user")
+	noCacheFlag := flag.Bool("no-cache", false, "Ignore and don't update the on-disk response cache")
+	recordFlag := flag.String("record", "", "Save every API request and response to this directory")
+	replayFlag := flag.String("replay", "", "Serve API requests from a directory saved with -record, without network")
+	var includeFlag, excludeFlag ruleList
+	flag.Var(&includeFlag, "include", "Only scan files whose name matches this glob or /regex/ (repeatable, replaces the profile's rules)")
+	flag.Var(&excludeFlag, "exclude", "Skip files whose name matches this glob or /regex/ (repeatable, replaces the profile's rules)")
+	filesFlag := flag.String("files", "", "Comma-separated file keys or Figma file URLs to scan directly (adds to -p or -proj)")
+	contextFlag := flag.String("context", "", "Named context (Figma account) to use instead of the current one")
 
 	flag.Parse()
 
-	// Check if running in CLI mode (any flag is set)
-	if flag.NFlag() > 0 {
+	fixtures := fixtureOptions{RecordDir: *recordFlag, ReplayDir: *replayFlag}
+	opts := cliOptions{
+		Profile:     *profileFlag,
+		Timeframe:   *timeframeFlag,
+		From:        *fromFlag,
+		To:          *toFlag,
+		Timezone:    *tzFlag,
+		NoAdvance:   *noAdvanceFlag,
+		Projects:    *projectsFlag,
+		Users:       *userFlag,
+		Format:      *formatFlag,
+		SaveReport:  *reportFlag,
+		Concurrency: *concurrencyFlag,
+		Teammates:   *teammatesFlag,
+		MyComments:  *myCommentsFlag,
+		NoCache:     *noCacheFlag,
+		Fixtures:    fixtures,
+		Context:     *contextFlag,
+		Files:       *filesFlag,
+		Include:     includeFlag.rules,
+		Exclude:     excludeFlag.rules,
+	}
+
+	// Subcommands
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Args(), opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := fixtures.prepare(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	// Check if running in CLI mode (any report flag is set)
+	cliMode := false
+	flag.Visit(func(f *flag.Flag) {
+		if !sharedFlags[f.Name] {
+			cliMode = true
+		}
+	})
+
+	if cliMode {
 		// CLI mode
-		err := runCLI(*profileFlag, *timeframeFlag, *projectsFlag, *userFlag, *formatFlag, *reportFlag)
+		err := runCLI(opts)
+		if errors.Is(err, errPartialScan) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			os.Exit(exitPartialScan)
+		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitError)
 		}
 		return
 	}
 
 	// TUI mode
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
+	m := initialModel(*contextFlag)
+	m.noCache = *noCacheFlag
+	m.fixtures = fixtures
+	p := tea.NewProgram(m, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v", err)
 		os.Exit(1)
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuth2 endpoints and defaults. Every URL can be overridden in config.json, e.g. to log in against a local stand-in server.
+const (
+	defaultOAuthAuthURL      = "https://www.figma.com/oauth"
+	defaultOAuthTokenURL     = "https://api.figma.com/v1/oauth/token"
+	defaultOAuthRefreshURL   = "https://api.figma.com/v1/oauth/refresh"
+	defaultOAuthCallbackPort = 8976
+	defaultOAuthScopes       = "current_user:read file_content:read file_metadata:read file_versions:read file_comments:read projects:read"
+
+	oauthLoginTimeout = 5 * time.Minute
+	// Refresh this long before the access token expires so a scan never starts with a token about to lapse
+	oauthRefreshMargin = 5 * time.Minute
+)
+
+// OAuth app settings, stored alongside the rest of config.json
+type oauthSettings struct {
+	ClientID     string `json:"oauth_client_id,omitempty"`
+	ClientSecret string `json:"oauth_client_secret,omitempty"`
+	AuthURL      string `json:"oauth_auth_url,omitempty"`
+	TokenURL     string `json:"oauth_token_url,omitempty"`
+	RefreshURL   string `json:"oauth_refresh_url,omitempty"`
+	CallbackPort int    `json:"oauth_callback_port,omitempty"`
+	Scopes       string `json:"oauth_scopes,omitempty"`
+}
+
+// withDefaults fills in every unset endpoint
+func (s oauthSettings) withDefaults() oauthSettings {
+	if s.AuthURL == "" {
+		s.AuthURL = defaultOAuthAuthURL
+	}
+	if s.TokenURL == "" {
+		s.TokenURL = defaultOAuthTokenURL
+	}
+	if s.RefreshURL == "" {
+		s.RefreshURL = defaultOAuthRefreshURL
+	}
+	if s.CallbackPort <= 0 {
+		s.CallbackPort = defaultOAuthCallbackPort
+	}
+	if s.Scopes == "" {
+		s.Scopes = defaultOAuthScopes
+	}
+	return s
+}
+
+func (s oauthSettings) redirectURI() string {
+	return fmt.Sprintf("http://localhost:%d/callback", s.CallbackPort)
+}
+
+// oauthToken is what `figma-beacon login` stores
+type oauthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// oauthSession hands out a valid access token, refreshing and persisting it when it is about to expire
+type oauthSession struct {
+	settings   oauthSettings
+	httpClient *http.Client
+	save       func(oauthToken) error
+
+	mu    sync.Mutex
+	token oauthToken
+}
+
+// authorize makes client authenticate with the OAuth login in cfg, if there is one; it wins over a personal access token.
+// cfg holds the account of contextName, which is where refreshed tokens are saved.
+func authorize(client *FigmaClient, cfg config, contextName string) {
+	if cfg.OAuth == nil || cfg.OAuth.AccessToken == "" {
+		return
+	}
+
+	client.Auth = &oauthSession{
+		settings:   cfg.oauthSettings.withDefaults(),
+		httpClient: &http.Client{Timeout: client.HTTPClient.Timeout},
+		save: func(token oauthToken) error {
+			return saveOAuthToken(contextName, token)
+		},
+		token: *cfg.OAuth,
+	}
+}
+
+// saveOAuthToken writes a refreshed token back to the context it belongs to
+func saveOAuthToken(contextName string, token oauthToken) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	account, err := cfg.withContext(contextName)
+	if err != nil {
+		return err
+	}
+	account.OAuth = &token
+	cfg.storeContext(contextName, account)
+	return saveConfig(cfg)
+}
+
+func (s *oauthSession) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token.ExpiresAt.IsZero() || time.Until(s.token.ExpiresAt) > oauthRefreshMargin {
+		return s.token.AccessToken, nil
+	}
+	if s.token.RefreshToken == "" {
+		return "", fmt.Errorf("OAuth token expired. Run 'figma-beacon login' again")
+	}
+
+	refreshed, err := requestToken(ctx, s.httpClient, s.settings, s.settings.RefreshURL, url.Values{
+		"refresh_token": {s.token.RefreshToken},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh OAuth token: %w", err)
+	}
+	// The refresh endpoint doesn't always issue a new refresh token
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = s.token.RefreshToken
+	}
+	s.token = refreshed
+
+	if s.save != nil {
+		if err := s.save(refreshed); err != nil {
+			return "", fmt.Errorf("failed to save refreshed OAuth token: %w", err)
+		}
+	}
+
+	return s.token.AccessToken, nil
+}
+
+// requestToken posts to a token or refresh endpoint, authenticating the app with HTTP Basic auth
+func requestToken(ctx context.Context, httpClient *http.Client, settings oauthSettings, endpoint string, form url.Values) (oauthToken, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oauthToken{}, err
+	}
+	req.SetBasicAuth(settings.ClientID, settings.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return oauthToken{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return oauthToken{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return oauthToken{}, &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"` // seconds
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return oauthToken{}, err
+	}
+	if result.AccessToken == "" {
+		return oauthToken{}, fmt.Errorf("token response has no access_token")
+	}
+
+	token := oauthToken{
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+	}
+	if result.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// oauthCallback is what the redirect to the callback server carried
+type oauthCallback struct {
+	code string
+	err  error
+}
+
+// authorizeLogin runs the browser part of the authorization-code flow on listener: it prints the consent URL to out,
+// asks open to show it, waits for the redirect and exchanges the code for tokens. Kept apart from runLogin so the
+// flow can run against a stand-in auth server.
+func authorizeLogin(ctx context.Context, settings oauthSettings, listener net.Listener, out io.Writer, open func(string)) (oauthToken, error) {
+	state, err := randomState()
+	if err != nil {
+		return oauthToken{}, err
+	}
+
+	results := make(chan oauthCallback, 1)
+	mux := http.NewServeMux()
+	mux.Handle("/callback", callbackHandler(state, results))
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authURL := settings.AuthURL + "?" + url.Values{
+		"client_id":     {settings.ClientID},
+		"redirect_uri":  {settings.redirectURI()},
+		"scope":         {settings.Scopes},
+		"state":         {state},
+		"response_type": {"code"},
+	}.Encode()
+
+	fmt.Fprintf(out, "Opening Figma to authorize figma-beacon. If the browser doesn't open, visit:\n\n  %s\n\n", authURL)
+	open(authURL)
+
+	var result oauthCallback
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return oauthToken{}, fmt.Errorf("timed out waiting for authorization")
+		}
+		return oauthToken{}, fmt.Errorf("login cancelled")
+	}
+	if result.err != nil {
+		return oauthToken{}, result.err
+	}
+
+	token, err := requestToken(ctx, &http.Client{Timeout: defaultAPITimeout * time.Second}, settings, settings.TokenURL, url.Values{
+		"redirect_uri": {settings.redirectURI()},
+		"code":         {result.code},
+		"grant_type":   {"authorization_code"},
+	})
+	if err != nil {
+		return oauthToken{}, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+	return token, nil
+}
+
+// callbackHandler answers the redirect from the consent page and passes on its code, or why there is none.
+// Only the first callback counts.
+func callbackHandler(state string, results chan<- oauthCallback) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		var result oauthCallback
+		switch {
+		case query.Get("state") != state:
+			result.err = fmt.Errorf("callback state doesn't match; try logging in again")
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("authorization denied: %s", query.Get("error"))
+		case query.Get("code") == "":
+			result.err = fmt.Errorf("callback has no authorization code")
+		default:
+			result.code = query.Get("code")
+		}
+
+		if result.err != nil {
+			http.Error(w, result.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Figma Beacon is logged in. You can close this tab.")
+		}
+
+		select {
+		case results <- result:
+		default:
+		}
+	})
+}
+
+// runLogin runs the OAuth2 authorization-code flow: it opens the browser on Figma's consent page,
+// waits for the redirect on a localhost callback server and exchanges the code for tokens.
+// The login is stored in the active context, so each context can be a different Figma account.
+func runLogin(contextFlag string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	contextName, _ := activeContextName(cfg, contextFlag)
+	account, err := cfg.withContext(contextName)
+	if err != nil {
+		return err
+	}
+
+	settings := cfg.oauthSettings.withDefaults()
+	if settings.ClientID == "" || settings.ClientSecret == "" {
+		return fmt.Errorf("OAuth app not configured. Set oauth_client_id and oauth_client_secret in config.json")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, oauthLoginTimeout)
+	defer cancel()
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", settings.CallbackPort))
+	if err != nil {
+		return fmt.Errorf("failed to start callback server: %w", err)
+	}
+
+	token, err := authorizeLogin(ctx, settings, listener, os.Stderr, openBrowser)
+	if err != nil {
+		return err
+	}
+	account.OAuth = &token
+	cfg.storeContext(contextName, account)
+	if err := saveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	// Fill in the user the token belongs to, as the Setup screen's Gather does
+	client := NewFigmaClient("", cfg.apiSettings)
+	authorize(client, account, contextName)
+	user, err := client.Me(ctx)
+	if err != nil {
+		return fmt.Errorf("logged in, but failed to fetch user info: %w", err)
+	}
+
+	// Reload: fetching may have refreshed and saved the token
+	if cfg, err = loadConfig(); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if account, err = cfg.withContext(contextName); err != nil {
+		return err
+	}
+	account.UserID = user.ID
+	account.UserHandle = user.Handle
+	account.UserEmail = user.Email
+	cfg.storeContext(contextName, account)
+
+	if err := saveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Logged in as %s (%s) in context '%s'\n", user.Handle, user.Email, contextName)
+	return nil
+}
+
+// runLogout forgets the active context's OAuth tokens; a personal access token, if set, is used again
+func runLogout(contextFlag string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	contextName, _ := activeContextName(cfg, contextFlag)
+	account, err := cfg.withContext(contextName)
+	if err != nil {
+		return err
+	}
+	if account.OAuth == nil {
+		fmt.Fprintln(os.Stderr, "Not logged in")
+		return nil
+	}
+
+	account.OAuth = nil
+	cfg.storeContext(contextName, account)
+	if err := saveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "Logged out")
+	return nil
+}
+
+func randomState() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// openBrowser tries the platform's URL opener; failure is fine since the URL is also printed
+func openBrowser(target string) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	if cmd.Start() == nil {
+		go cmd.Wait()
+	}
+}
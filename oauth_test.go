@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// standInAuthServer plays Figma's token and refresh endpoints, recording the forms it receives
+type standInAuthServer struct {
+	*httptest.Server
+	forms map[string]url.Values // by path
+}
+
+func newStandInAuthServer(t *testing.T) *standInAuthServer {
+	t.Helper()
+	s := &standInAuthServer{forms: make(map[string]url.Values)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "client" || pass != "secret" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.forms[r.URL.Path] = r.PostForm
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			if r.PostForm.Get("code") != "good-code" {
+				http.Error(w, `{"error":"invalid_grant","message":"bad code"}`, http.StatusBadRequest)
+				return
+			}
+			io.WriteString(w, `{"access_token":"access-1","refresh_token":"refresh-1","expires_in":3600}`)
+		case "/refresh":
+			// Figma's refresh response carries no new refresh token
+			io.WriteString(w, `{"access_token":"access-2","expires_in":7200}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// loginSettings points the flow at the stand-in server and a callback listener on a free port
+func loginSettings(t *testing.T, server *standInAuthServer) (oauthSettings, net.Listener) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := oauthSettings{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		AuthURL:      server.URL + "/oauth",
+		TokenURL:     server.URL + "/token",
+		RefreshURL:   server.URL + "/refresh",
+		CallbackPort: listener.Addr().(*net.TCPAddr).Port,
+	}
+	return settings.withDefaults(), listener
+}
+
+// browser stands in for the user approving the consent page: it follows the redirect with the given code,
+// echoing the state from the consent URL unless state is set
+func browser(t *testing.T, code, state string) func(string) {
+	return func(authURL string) {
+		parsed, err := url.Parse(authURL)
+		if err != nil {
+			t.Errorf("invalid consent URL %q: %v", authURL, err)
+			return
+		}
+		query := parsed.Query()
+		if state == "" {
+			state = query.Get("state")
+		}
+		callback := query.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {state}}.Encode()
+		go func() {
+			resp, err := http.Get(callback)
+			if err == nil {
+				resp.Body.Close()
+			}
+		}()
+	}
+}
+
+func TestAuthorizeLogin(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      string
+		state     string // empty echoes the real one
+		wantErr   string
+		exchanged bool
+	}{
+		{name: "exchanges the code", code: "good-code", exchanged: true},
+		{name: "rejects a state mismatch", code: "good-code", state: "forged", wantErr: "state doesn't match"},
+		{name: "reports a refused code", code: "bad-code", wantErr: "failed to exchange authorization code", exchanged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStandInAuthServer(t)
+			settings, listener := loginSettings(t, server)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			var out strings.Builder
+			token, err := authorizeLogin(ctx, settings, listener, &out, browser(t, tt.code, tt.state))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			form, exchanged := server.forms["/token"]
+			if exchanged != tt.exchanged {
+				t.Fatalf("token endpoint called = %v, want %v", exchanged, tt.exchanged)
+			}
+			if !strings.Contains(out.String(), settings.AuthURL+"?") {
+				t.Errorf("consent URL not printed: %q", out.String())
+			}
+			if tt.wantErr != "" {
+				return
+			}
+
+			if form.Get("grant_type") != "authorization_code" || form.Get("redirect_uri") != settings.redirectURI() {
+				t.Errorf("token request form = %v", form)
+			}
+			if token.AccessToken != "access-1" || token.RefreshToken != "refresh-1" {
+				t.Errorf("token = %+v", token)
+			}
+			if until := time.Until(token.ExpiresAt); until < 59*time.Minute || until > time.Hour {
+				t.Errorf("token expires in %v, want about an hour", until)
+			}
+		})
+	}
+}
+
+func TestOAuthSessionRefresh(t *testing.T) {
+	server := newStandInAuthServer(t)
+	settings, listener := loginSettings(t, server)
+	listener.Close()
+
+	var saved []oauthToken
+	session := &oauthSession{
+		settings:   settings,
+		httpClient: server.Client(),
+		save: func(token oauthToken) error {
+			saved = append(saved, token)
+			return nil
+		},
+		token: oauthToken{AccessToken: "access-1", RefreshToken: "refresh-1", ExpiresAt: time.Now().Add(time.Minute)},
+	}
+
+	// Within the refresh margin, so the first call refreshes
+	token, err := session.accessToken(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "access-2" {
+		t.Errorf("access token = %q, want the refreshed one", token)
+	}
+	if got := server.forms["/refresh"].Get("refresh_token"); got != "refresh-1" {
+		t.Errorf("refresh request sent refresh_token %q", got)
+	}
+	if len(saved) != 1 || saved[0].RefreshToken != "refresh-1" {
+		t.Fatalf("saved tokens = %+v, want one keeping the old refresh token", saved)
+	}
+
+	// Now valid for two hours, so no second refresh
+	if token, err = session.accessToken(context.Background()); err != nil || token != "access-2" {
+		t.Errorf("second call = %q, %v", token, err)
+	}
+	if len(saved) != 1 {
+		t.Errorf("refreshed again while the token was still valid")
+	}
+}
+
+func TestOAuthSessionExpiredWithoutRefreshToken(t *testing.T) {
+	session := &oauthSession{token: oauthToken{AccessToken: "access-1", ExpiresAt: time.Now().Add(-time.Minute)}}
+	if _, err := session.accessToken(context.Background()); err == nil || !strings.Contains(err.Error(), "login") {
+		t.Errorf("error = %v, want a hint to log in again", err)
+	}
+}
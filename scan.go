@@ -0,0 +1,503 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultConcurrency = 4
+
+// scanOptions tunes how a scan runs and whose activity it reports
+type scanOptions struct {
+	Concurrency      int          // Files fetched in parallel
+	UserIDs          []string     // Users whose saved versions count as "my" changes; empty means anyone
+	IncludeTeammates bool         // Also report files only other users changed
+	UserHandle       string       // Used to spot @mentions of the configured user in comments
+	MyCommentsOnly   bool         // Keep only comments written by, or mentioning, the configured users
+	Filter           fileFilter   // Leaves out files of the projects by name; watched files are always scanned
+	Branches         *branchStore // Remembers listed branches so archived ones are still scanned; nil to forget
+
+	// Progress, if set, is called as projects are listed and files are scanned. Calls never overlap.
+	Progress func(scanProgress)
+}
+
+// scanProgress is a snapshot of how far a scan has got
+type scanProgress struct {
+	Project  int // Project being listed, then the project of the last scanned file
+	Projects int
+	File     int // Files scanned so far
+	Files    int // Files to scan; zero while projects are still being listed
+	Skipped  int // Files unchanged since the window start, whose history isn't fetched
+}
+
+func (p scanProgress) String() string {
+	if p.Files == 0 && p.File == 0 {
+		return fmt.Sprintf("project %d/%d, listing files", p.Project, p.Projects)
+	}
+	return fmt.Sprintf("project %d/%d, file %d/%d, %d skipped", p.Project, p.Projects, p.File, p.Files, p.Skipped)
+}
+
+// splitList parses a comma-separated flag value, dropping blanks
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// resolveConcurrency picks the worker count: flag, then profile, then config, then the default
+func resolveConcurrency(flagValue int, profile *Profile, configValue int) int {
+	if flagValue > 0 {
+		return flagValue
+	}
+	if profile != nil && profile.Concurrency > 0 {
+		return profile.Concurrency
+	}
+	if configValue > 0 {
+		return configValue
+	}
+	return defaultConcurrency
+}
+
+// scanJob is one file to check, along with the profile project it was listed under
+type scanJob struct {
+	file         FigmaFile
+	project      ProfileProject
+	projectIndex int        // 1-based, for progress
+	main         *FigmaFile // set for branches
+	archived     bool       // a remembered branch that is no longer listed
+	unchanged    bool       // last modified before the window: only its comments are checked
+}
+
+type scanResult struct {
+	activity FileActivity
+	active   bool
+	err      error
+	scanned  bool // false when the scan was cancelled before the file was done
+}
+
+// scanActivity walks the profile's projects and builds an activity report for the time window.
+// Both the TUI and the CLI go through here so the two report paths stay identical.
+func scanActivity(ctx context.Context, client *FigmaClient, profile *Profile, window TimeWindow, userID, userHandle string, opts scanOptions) *ActivityReport {
+	progress := scanProgress{Projects: len(profile.SelectedProjects)}
+	// The watchlist counts as one more project in progress reports
+	if len(profile.Files) > 0 {
+		progress.Projects++
+	}
+	notify := func() {
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+
+	// List every project first so files can be fetched in parallel across projects
+	var jobs []scanJob
+	var warnings []ReportWarning
+	filteredOut := 0
+	for i, project := range profile.SelectedProjects {
+		if ctx.Err() != nil {
+			break
+		}
+		progress.Project = i + 1
+		notify()
+
+		projectFiles, err := client.ProjectFiles(ctx, project.ID)
+		if err != nil && ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			warnings = append(warnings, ReportWarning{
+				Scope:       "project",
+				ProjectName: project.Name,
+				Message:     err.Error(),
+			})
+			continue
+		}
+
+		for _, fileInfo := range projectFiles {
+			if !opts.Filter.allows(fileInfo.Name) {
+				filteredOut++
+				continue
+			}
+			// A file last modified before the window can't have been created or edited in it,
+			// but commenting doesn't change last_modified, so its comments are still checked
+			unchanged := !fileInfo.LastModified.IsZero() && fileInfo.LastModified.Before(window.Start)
+			if unchanged {
+				progress.Skipped++
+			}
+			jobs = append(jobs, scanJob{file: fileInfo, project: project, projectIndex: i + 1, unchanged: unchanged})
+
+			// Branches are queued right after their main file, whether or not it changed
+			main := fileInfo
+			main.Branches = nil
+			for _, branch := range fileInfo.Branches {
+				branchFile := FigmaFile{Key: branch.Key, Name: branch.Name, LastModified: branch.LastModified, ProjectID: fileInfo.ProjectID}
+				unchanged := !branch.LastModified.IsZero() && branch.LastModified.Before(window.Start)
+				if unchanged {
+					progress.Skipped++
+				}
+				jobs = append(jobs, scanJob{file: branchFile, project: project, projectIndex: i + 1, main: &main, unchanged: unchanged})
+			}
+			for _, branch := range opts.Branches.update(fileInfo.Key, fileInfo.Branches, time.Now()) {
+				// Archived branches can't be edited, so the last listed modification still holds
+				unchanged := branch.LastModified.Before(window.Start)
+				if unchanged {
+					progress.Skipped++
+				}
+				branchFile := FigmaFile{Key: branch.Key, Name: branch.Name, ProjectID: fileInfo.ProjectID}
+				jobs = append(jobs, scanJob{file: branchFile, project: project, projectIndex: i + 1, main: &main, archived: true, unchanged: unchanged})
+			}
+		}
+	}
+	// Watched files are checked directly, so a handful of key files doesn't mean listing their whole projects
+	if len(profile.Files) > 0 && ctx.Err() == nil {
+		progress.Project = progress.Projects
+		notify()
+
+		queued := make(map[string]bool)
+		for _, job := range jobs {
+			queued[job.file.Key] = true
+		}
+		for _, file := range profile.Files {
+			if queued[file.Key] {
+				continue
+			}
+			queued[file.Key] = true
+			jobs = append(jobs, scanJob{
+				file:         FigmaFile{Key: file.Key, Name: file.Name},
+				project:      ProfileProject{Name: watchedFilesProject},
+				projectIndex: progress.Projects,
+			})
+		}
+	}
+
+	progress.Files = len(jobs)
+	if len(jobs) > 0 {
+		progress.Project = jobs[0].projectIndex
+	}
+	notify()
+
+	results := runScanJobs(ctx, client, jobs, window, opts, func(i int) {
+		progress.File++
+		progress.Project = jobs[i].projectIndex
+		notify()
+	})
+
+	// Assemble in listing order so the report is the same whatever order workers finished in
+	var files []FileActivity
+	mainVersions := make(map[string][]FigmaVersion)
+	unscanned := 0
+	for i, result := range results {
+		if !result.scanned {
+			unscanned++
+			continue
+		}
+		// A remembered branch that is gone wasn't archived but deleted; there is nothing left to report
+		if jobs[i].archived && isNotFound(result.err) {
+			opts.Branches.forget(jobs[i].main.Key, jobs[i].file.Key)
+			continue
+		}
+		if result.err != nil {
+			warnings = append(warnings, ReportWarning{
+				Scope:       "file",
+				ProjectName: jobs[i].project.Name,
+				FileKey:     jobs[i].file.Key,
+				FileName:    jobs[i].file.Name,
+				Message:     result.err.Error(),
+			})
+			continue
+		}
+		// Main files' versions are kept even when inactive, to tell merged branches from archived ones
+		if jobs[i].main == nil {
+			mainVersions[jobs[i].file.Key] = result.activity.Versions
+		}
+		if result.active {
+			files = append(files, result.activity)
+		}
+	}
+
+	// Best effort, like the response cache: failing to remember branches only costs archived ones next time
+	_ = opts.Branches.save()
+
+	// Build report
+	report := &ActivityReport{
+		TimeWindow:   window,
+		UserID:       userID,
+		UserHandle:   userHandle,
+		Files:        nestBranches(files, mainVersions),
+		Warnings:     warnings,
+		TotalFiles:   len(files),
+		TotalChanges: 0,
+		GeneratedAt:  time.Now(),
+		Teammates:    opts.IncludeTeammates,
+		MyComments:   opts.MyCommentsOnly,
+		Cancelled:    ctx.Err() != nil,
+		Unscanned:    unscanned,
+		FilteredOut:  filteredOut,
+		Timeline:     buildTimeline(window, files),
+	}
+
+	// Count total changes, branches included
+	for _, file := range files {
+		if file.MyChanges {
+			report.TotalChanges++
+		}
+		report.TotalComments += len(file.Comments)
+	}
+
+	return report
+}
+
+// runScanJobs fetches files with a bounded pool of workers.
+// Results are indexed like jobs; workers share the client, so a 429 pauses all of them.
+// done is called once per finished file, never concurrently. Cancelling ctx stops handing out files.
+func runScanJobs(ctx context.Context, client *FigmaClient, jobs []scanJob, window TimeWindow, opts scanOptions, done func(i int)) []scanResult {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	if concurrency > len(jobs) {
+		concurrency = len(jobs)
+	}
+
+	results := make([]scanResult, len(jobs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	var doneMu sync.Mutex
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				activity, active, err := scanFile(ctx, client, jobs[i], window, opts)
+				// A request cut short by cancellation says nothing about the file
+				if err != nil && ctx.Err() != nil {
+					continue
+				}
+				results[i] = scanResult{activity: activity, active: active, err: err, scanned: true}
+
+				doneMu.Lock()
+				done(i)
+				doneMu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for i := range jobs {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// scanFile fetches metadata and version history for a single file.
+// It reports false when the file has no activity in the window worth reporting.
+func scanFile(ctx context.Context, client *FigmaClient, job scanJob, window TimeWindow, opts scanOptions) (FileActivity, bool, error) {
+	fileInfo, project := job.file, job.project
+	if job.unchanged {
+		return scanComments(ctx, client, job, fileInfo.Name, fileInfo.LastModified, window, opts)
+	}
+
+	meta, err := client.FileMeta(ctx, fileInfo.Key, fileInfo.LastModified)
+	if err != nil {
+		return FileActivity{}, false, err
+	}
+
+	// Watched files weren't listed, so only now is it known whether they changed since the window start
+	if fileInfo.LastModified.IsZero() && !meta.LastModified.IsZero() && meta.LastModified.Before(window.Start) {
+		return scanComments(ctx, client, job, meta.Name, meta.LastModified, window, opts)
+	}
+
+	// Walk version history back to the window start. If that reaches the first version we also learn
+	// the creation date; if not, the file was created before the window.
+	versions, complete, err := client.FileHistory(ctx, fileInfo.Key, window.Start, meta.Version)
+	if err != nil {
+		return FileActivity{}, false, err
+	}
+
+	createdAt, known := client.creationDate(fileInfo.Key)
+	if !known && complete && len(versions) > 0 {
+		createdAt = versions[len(versions)-1].Created
+		client.setCreationDate(fileInfo.Key, createdAt)
+	}
+
+	// Check if file was created in the time window
+	createdInWindow := !createdAt.IsZero() && window.contains(createdAt)
+
+	// Attribute the window's versions to the configured users or to teammates
+	mine := userSet(opts.UserIDs)
+
+	myChanges := false
+	teammates := make(map[string]bool)
+	windowVersions := []FigmaVersion{}
+	for i := len(versions) - 1; i >= 0; i-- {
+		version := versions[i]
+		if !window.contains(version.Created) {
+			continue
+		}
+		version.Created = version.Created.In(window.Start.Location())
+		windowVersions = append(windowVersions, version)
+		if len(mine) == 0 || mine[version.User.ID] {
+			myChanges = true
+		} else {
+			teammates[version.User.Handle] = true
+		}
+	}
+
+	// Edits newer than the last saved version have no author yet. Without configured users every change
+	// counts as mine; otherwise they may be the user's own unsaved work, so they are reported as unattributed.
+	modifiedInWindow := window.contains(meta.LastModified)
+	unattributed := modifiedInWindow && len(windowVersions) == 0
+	if unattributed && len(mine) == 0 {
+		myChanges = true
+	}
+	unattributedChanges := unattributed && len(mine) > 0
+
+	// A file created in the window is only "mine" if one of my users saved its first version
+	createdByMe := createdInWindow && len(mine) == 0
+	if createdInWindow && len(mine) > 0 && len(versions) > 0 {
+		creator := versions[len(versions)-1].User
+		if mine[creator.ID] {
+			createdByMe = true
+		} else {
+			teammates[creator.Handle] = true
+		}
+	}
+
+	teammateChanges := len(teammates) > 0
+
+	comments, err := client.FileComments(ctx, fileInfo.Key)
+	if err != nil {
+		return FileActivity{}, false, err
+	}
+	comments = commentsInWindow(comments, window, mine, opts)
+
+	// Only include files with activity (created, modified or commented on in window)
+	active := myChanges || createdByMe || unattributedChanges || (opts.IncludeTeammates && teammateChanges) || len(comments) > 0
+
+	var teammateHandles []string
+	for handle := range teammates {
+		teammateHandles = append(teammateHandles, handle)
+	}
+	sort.Strings(teammateHandles)
+
+	activity := FileActivity{
+		FileKey:             fileInfo.Key,
+		FileName:            meta.Name,
+		ProjectName:         project.Name, // Use project name from profile
+		TeamID:              project.TeamID,
+		TeamName:            project.TeamName,
+		LastModified:        meta.LastModified,
+		CreatedAt:           createdAt,
+		MyChanges:           myChanges,
+		CreatedInWindow:     createdInWindow,
+		TeammateChanges:     teammateChanges,
+		UnattributedChanges: unattributedChanges,
+		Teammates:           teammateHandles,
+		Versions:            windowVersions, // oldest first
+		Comments:            comments,
+	}
+	// Report times in the window's zone, so dates read the same as the window
+	activity.LastModified = activity.LastModified.In(window.Start.Location())
+	if !createdAt.IsZero() {
+		activity.CreatedAt = createdAt.In(window.Start.Location())
+	}
+	job.describeBranch(&activity)
+	return activity, active, nil
+}
+
+// scanComments checks a file that wasn't edited in the window for comments left in it.
+// Commenting doesn't change a file's last_modified, so review feedback on untouched files only shows up here.
+func scanComments(ctx context.Context, client *FigmaClient, job scanJob, name string, lastModified time.Time, window TimeWindow, opts scanOptions) (FileActivity, bool, error) {
+	comments, err := client.FileComments(ctx, job.file.Key)
+	if err != nil {
+		return FileActivity{}, false, err
+	}
+	comments = commentsInWindow(comments, window, userSet(opts.UserIDs), opts)
+	if len(comments) == 0 {
+		return FileActivity{}, false, nil
+	}
+
+	activity := FileActivity{
+		FileKey:      job.file.Key,
+		FileName:     name,
+		ProjectName:  job.project.Name,
+		TeamID:       job.project.TeamID,
+		TeamName:     job.project.TeamName,
+		LastModified: lastModified.In(window.Start.Location()),
+		Versions:     []FigmaVersion{},
+		Comments:     comments,
+	}
+	job.describeBranch(&activity)
+	return activity, true, nil
+}
+
+// describeBranch links a branch's activity to its main file
+func (job scanJob) describeBranch(activity *FileActivity) {
+	if job.main == nil {
+		return
+	}
+	// The listing names the branch itself; the file's metadata may carry the main file's name
+	if job.file.Name != "" {
+		activity.FileName = job.file.Name
+	}
+	activity.MainFileKey = job.main.Key
+	activity.MainFileName = job.main.Name
+	if job.archived {
+		activity.BranchStatus = branchArchived
+	}
+}
+
+// userSet turns the configured user IDs into a lookup
+func userSet(ids []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// commentsInWindow keeps the comments created in the window, oldest first.
+// With MyCommentsOnly set it also drops comments that neither come from nor mention the configured users.
+func commentsInWindow(comments []FigmaComment, window TimeWindow, mine map[string]bool, opts scanOptions) []FigmaComment {
+	mention := ""
+	if opts.UserHandle != "" {
+		mention = "@" + strings.ToLower(opts.UserHandle)
+	}
+
+	kept := []FigmaComment{}
+	for _, comment := range comments {
+		if !window.contains(comment.CreatedAt) {
+			continue
+		}
+		comment.CreatedAt = comment.CreatedAt.In(window.Start.Location())
+		if opts.MyCommentsOnly {
+			byMe := mine[comment.User.ID]
+			mentionsMe := mention != "" && strings.Contains(strings.ToLower(comment.Message), mention)
+			if !byMe && !mentionsMe {
+				continue
+			}
+		}
+		kept = append(kept, comment)
+	}
+
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].CreatedAt.Before(kept[j].CreatedAt)
+	})
+	return kept
+}
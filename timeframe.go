@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timeframes beyond the fixed modes: durations (10d, 6w), days and longer periods (2026-09-15, 2026-09,
+// 2026-w37, 2026-q3, 2026, today, last-monday) and ranges of them (last-monday..today, 2026-07..2026-09).
+// They resolve to a custom window.
+const timeframeHelp = "week, month, m2d, 4w, 30d, prev-week, this-week, prev-quarter, quarter, ytd, prev-year, sprint, sprint-<n>, since-last, " +
+	"<n>d, <n>w, a date or period (2026-09, 2026-w37, 2026-q3), or a range (last-monday..today)"
+
+// Sprint is a profile's sprint cadence: the first day of any one sprint and the length of every sprint
+type Sprint struct {
+	Start  string `json:"start"`  // e.g. "2026-01-05"
+	Length int    `json:"length"` // days
+}
+
+// parseTimeframe turns a -t value into a report configuration. sprint is the profile's, if it has one.
+func parseTimeframe(value string, now time.Time, sprint *Sprint) (ReportConfig, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "week", "7d":
+		return ReportConfig{TimeMode: timeModeLastWeek}, nil
+	case "month":
+		return ReportConfig{TimeMode: timeModeLastMonth}, nil
+	case "m2d", "mtd":
+		return ReportConfig{TimeMode: timeModeThisMonthToDate}, nil
+	case "4w", "28d":
+		return ReportConfig{TimeMode: timeModeLast4Weeks}, nil
+	case "30d":
+		return ReportConfig{TimeMode: timeModeLast30Days}, nil
+	case "prev-week", "last-isoweek":
+		return ReportConfig{TimeMode: timeModePrevWeek}, nil
+	case "this-week":
+		return ReportConfig{TimeMode: timeModeThisWeek}, nil
+	case "prev-quarter":
+		return ReportConfig{TimeMode: timeModePrevQuarter}, nil
+	case "quarter", "this-quarter", "qtd":
+		return ReportConfig{TimeMode: timeModeThisQuarter}, nil
+	case "ytd":
+		return ReportConfig{TimeMode: timeModeYearToDate}, nil
+	case "prev-year":
+		return ReportConfig{TimeMode: timeModePrevYear}, nil
+	case "since-last":
+		return ReportConfig{TimeMode: timeModeSinceLast}, nil
+	}
+
+	// sprint is the current sprint, sprint-1 the one before it
+	if rest, ok := strings.CutPrefix(value, "sprint"); ok {
+		back := 0
+		if rest != "" {
+			n, err := strconv.Atoi(strings.TrimPrefix(rest, "-"))
+			if !strings.HasPrefix(rest, "-") || err != nil || n < 0 {
+				return ReportConfig{}, fmt.Errorf("invalid timeframe '%s'. Use sprint or sprint-<n>", value)
+			}
+			back = n
+		}
+		config := ReportConfig{TimeMode: timeModeSprint, Sprint: sprint, SprintsBack: back}
+		if err := sprint.validate(); err != nil {
+			return ReportConfig{}, err
+		}
+		return config, nil
+	}
+
+	if days, ok := parseDuration(value); ok {
+		return customConfig(now.AddDate(0, 0, -days), now), nil
+	}
+
+	from, to, isRange := strings.Cut(value, "..")
+	if !isRange {
+		to = from
+	}
+	config, err := customRange(from, to, now)
+	if err != nil {
+		return ReportConfig{}, fmt.Errorf("invalid timeframe '%s': %w. Valid options: %s", value, err, timeframeHelp)
+	}
+	return config, nil
+}
+
+// customRange builds a window from the start of the from day (or month) to the end of the to day (or month).
+// An empty to means up to now. Days are counted in now's zone.
+func customRange(from, to string, now time.Time) (ReportConfig, error) {
+	start, _, err := parseDay(from, now)
+	if err != nil {
+		return ReportConfig{}, err
+	}
+	if start.After(now) {
+		return ReportConfig{}, fmt.Errorf("'%s' is in the future", from)
+	}
+
+	end := now
+	if strings.TrimSpace(to) != "" {
+		_, next, err := parseDay(to, now)
+		if err != nil {
+			return ReportConfig{}, err
+		}
+		// The window ends where the next day starts, but never in the future
+		if end = next; end.After(now) {
+			end = now
+		}
+	}
+
+	if !start.Before(end) {
+		return ReportConfig{}, fmt.Errorf("'%s' is not before '%s'", from, to)
+	}
+	return customConfig(start, end), nil
+}
+
+func customConfig(start, end time.Time) ReportConfig {
+	return ReportConfig{TimeMode: timeModeCustom, From: start, To: end}
+}
+
+// parseDuration reads <n>d or <n>w as a number of days
+func parseDuration(value string) (int, bool) {
+	if len(value) < 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	switch value[len(value)-1] {
+	case 'd':
+		return n, true
+	case 'w':
+		return n * 7, true
+	}
+	return 0, false
+}
+
+var weekOrQuarter = regexp.MustCompile(`^(\d{4})-([wq])(\d{1,2})$`)
+
+// parseDay resolves a day, ISO week, month, quarter or year to its first moment and the first moment after it
+func parseDay(expr string, now time.Time) (time.Time, time.Time, error) {
+	expr = strings.ToLower(strings.TrimSpace(expr))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch expr {
+	case "":
+		return time.Time{}, time.Time{}, fmt.Errorf("missing date")
+	case "today":
+		return today, today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, nil
+	}
+
+	// last-monday is the most recent Monday before today
+	if name, ok := strings.CutPrefix(expr, "last-"); ok {
+		for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+			if strings.ToLower(weekday.String()) != name {
+				continue
+			}
+			back := (int(today.Weekday()) - int(weekday) + 7) % 7
+			if back == 0 {
+				back = 7
+			}
+			day := today.AddDate(0, 0, -back)
+			return day, day.AddDate(0, 0, 1), nil
+		}
+		return time.Time{}, time.Time{}, fmt.Errorf("unknown day '%s'", expr)
+	}
+
+	if day, err := time.ParseInLocation("2006-01-02", expr, now.Location()); err == nil {
+		return day, day.AddDate(0, 0, 1), nil
+	}
+	if month, err := time.ParseInLocation("2006-01", expr, now.Location()); err == nil {
+		return month, month.AddDate(0, 1, 0), nil
+	}
+	if year, err := time.ParseInLocation("2006", expr, now.Location()); err == nil {
+		return year, year.AddDate(1, 0, 0), nil
+	}
+
+	// 2026-w37 and 2026-q3
+	if match := weekOrQuarter.FindStringSubmatch(expr); match != nil {
+		year, _ := strconv.Atoi(match[1])
+		unit := match[2]
+		n, _ := strconv.Atoi(match[3])
+		switch {
+		case unit == "w" && n >= 1 && n <= 53:
+			week := startOfISOWeek(time.Date(year, time.January, 4, 0, 0, 0, 0, now.Location())).AddDate(0, 0, (n-1)*7)
+			if _, w := week.ISOWeek(); w == n {
+				return week, week.AddDate(0, 0, 7), nil
+			}
+		case unit == "q" && n >= 1 && n <= 4:
+			quarter := time.Date(year, time.Month((n-1)*3+1), 1, 0, 0, 0, 0, now.Location())
+			return quarter, quarter.AddDate(0, 3, 0), nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("'%s' is not a date (2026-09-15), week (2026-w37), month (2026-09), quarter (2026-q3), year, today, yesterday or last-<weekday>", expr)
+}
+
+// startOfISOWeek returns midnight on the Monday of t's ISO week
+func startOfISOWeek(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// startOfQuarter returns midnight on the first day of t's quarter
+func startOfQuarter(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month()-(t.Month()-1)%3, 1, 0, 0, 0, 0, t.Location())
+}
+
+func (s *Sprint) validate() error {
+	if s == nil {
+		return fmt.Errorf("the profile has no sprint. Add \"sprint\": {\"start\": \"2026-01-05\", \"length\": 14} to its .beacon file")
+	}
+	if s.Length <= 0 {
+		return fmt.Errorf("the profile's sprint length must be a number of days")
+	}
+	if _, err := time.Parse("2006-01-02", s.Start); err != nil {
+		return fmt.Errorf("the profile's sprint start '%s' is not a date like 2026-01-05", s.Start)
+	}
+	return nil
+}
+
+// window returns the sprint containing now, or the one back sprints before it.
+// The current sprint runs to now; earlier ones end where the next sprint starts.
+func (s *Sprint) window(now time.Time, back int) (time.Time, time.Time) {
+	anchor, _ := time.ParseInLocation("2006-01-02", s.Start, now.Location())
+
+	// Count whole days on UTC dates so a DST change doesn't shift the count
+	day := func(t time.Time) int {
+		return int(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400)
+	}
+	elapsed := day(now) - day(anchor)
+	sprints := elapsed / s.Length
+	if elapsed < 0 && elapsed%s.Length != 0 {
+		sprints-- // round toward the past for anchors in the future
+	}
+
+	start := anchor.AddDate(0, 0, (sprints-back)*s.Length)
+	if back == 0 {
+		return start, now
+	}
+	return start, start.AddDate(0, 0, s.Length)
+}
+
+// reportLocation picks the zone report windows are counted in: flag, then profile, then config, then the machine's
+func reportLocation(flagValue string, profile *Profile, configValue string) (*time.Location, error) {
+	name := configValue
+	if profile != nil && profile.Timezone != "" {
+		name = profile.Timezone
+	}
+	if flagValue != "" {
+		name = flagValue
+	}
+	if name == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone '%s', use an IANA name like Europe/Madrid or UTC", name)
+	}
+	return location, nil
+}
+
+// zoneName names a zone for reports. The machine's zone has no IANA name to show, so its abbreviation is used.
+func zoneName(location *time.Location, now time.Time) string {
+	if location == time.Local {
+		name, _ := now.Zone()
+		return name
+	}
+	return location.String()
+}
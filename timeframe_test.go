@@ -0,0 +1,402 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// Every test counts days in Madrid from a fixed Friday afternoon
+var testZone = mustLoadLocation("Europe/Madrid")
+
+var testNow = time.Date(2026, time.October, 16, 15, 30, 0, 0, testZone)
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+// day is midnight in the test zone
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, testZone)
+}
+
+func TestParseDay(t *testing.T) {
+	tests := []struct {
+		expr        string
+		start, next time.Time
+		wantErr     bool
+	}{
+		{expr: "today", start: day(2026, 10, 16), next: day(2026, 10, 17)},
+		{expr: " Yesterday ", start: day(2026, 10, 15), next: day(2026, 10, 16)},
+		{expr: "last-monday", start: day(2026, 10, 12), next: day(2026, 10, 13)},
+		{expr: "last-friday", start: day(2026, 10, 9), next: day(2026, 10, 10)}, // today is Friday, so a week back
+		{expr: "2026-09-15", start: day(2026, 9, 15), next: day(2026, 9, 16)},
+		{expr: "2026-09", start: day(2026, 9, 1), next: day(2026, 10, 1)},
+		{expr: "2026-12", start: day(2026, 12, 1), next: day(2027, 1, 1)},
+		{expr: "", wantErr: true},
+		{expr: "last-funday", wantErr: true},
+		{expr: "2026-13", wantErr: true},
+		{expr: "2026-09-31", wantErr: true},
+		{expr: "tomorrowish", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			start, next, err := parseDay(tt.expr, testNow)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseDay(%q) = %v, %v, want an error", tt.expr, start, next)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDay(%q): %v", tt.expr, err)
+			}
+			if !start.Equal(tt.start) || !next.Equal(tt.next) {
+				t.Errorf("parseDay(%q) = %v, %v, want %v, %v", tt.expr, start, next, tt.start, tt.next)
+			}
+		})
+	}
+}
+
+func TestParseTimeframe(t *testing.T) {
+	tests := []struct {
+		value    string
+		mode     timeMode
+		from, to time.Time // custom windows only
+		wantErr  bool
+	}{
+		{value: "week", mode: timeModeLastWeek},
+		{value: "7d", mode: timeModeLastWeek},
+		{value: "month", mode: timeModeLastMonth},
+		{value: "M2D", mode: timeModeThisMonthToDate},
+		{value: "4w", mode: timeModeLast4Weeks},
+		{value: "30d", mode: timeModeLast30Days},
+
+		// Durations run up to now
+		{value: "10d", mode: timeModeCustom, from: testNow.AddDate(0, 0, -10), to: testNow},
+		{value: "2w", mode: timeModeCustom, from: testNow.AddDate(0, 0, -14), to: testNow},
+
+		// Ranges include both ends, but never reach past now
+		{value: "2026-07..2026-09", mode: timeModeCustom, from: day(2026, 7, 1), to: day(2026, 10, 1)},
+		{value: "last-monday..yesterday", mode: timeModeCustom, from: day(2026, 10, 12), to: day(2026, 10, 16)},
+		{value: "last-monday..today", mode: timeModeCustom, from: day(2026, 10, 12), to: testNow},
+		{value: "2026-09-01..", mode: timeModeCustom, from: day(2026, 9, 1), to: testNow},
+		{value: "2026-10-01", mode: timeModeCustom, from: day(2026, 10, 1), to: day(2026, 10, 2)},
+		{value: "2026-10", mode: timeModeCustom, from: day(2026, 10, 1), to: testNow},
+
+		{value: "fortnight", wantErr: true},
+		{value: "0d", wantErr: true},
+		{value: "2026-11-01", wantErr: true},             // in the future
+		{value: "2026-10-10..2026-10-01", wantErr: true}, // backwards
+		{value: "2026-10-01..someday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			config, err := parseTimeframe(tt.value, testNow, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseTimeframe(%q) = %+v, want an error", tt.value, config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTimeframe(%q): %v", tt.value, err)
+			}
+			if config.TimeMode != tt.mode {
+				t.Errorf("mode = %s, want %s", config.TimeMode, tt.mode)
+			}
+			if tt.mode == timeModeCustom && (!config.From.Equal(tt.from) || !config.To.Equal(tt.to)) {
+				t.Errorf("range = %v .. %v, want %v .. %v", config.From, config.To, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestParseDayPeriods(t *testing.T) {
+	tests := []struct {
+		expr        string
+		start, next time.Time
+		wantErr     bool
+	}{
+		{expr: "2026-w37", start: day(2026, 9, 7), next: day(2026, 9, 14)},
+		{expr: "2026-W01", start: day(2025, 12, 29), next: day(2026, 1, 5)}, // ISO week 1 starts in the previous year
+		{expr: "2026-w53", start: day(2026, 12, 28), next: day(2027, 1, 4)}, // 2026 starts on a Thursday, so it has 53 weeks
+		{expr: "2026-q3", start: day(2026, 7, 1), next: day(2026, 10, 1)},
+		{expr: "2026-q4", start: day(2026, 10, 1), next: day(2027, 1, 1)},
+		{expr: "2025", start: day(2025, 1, 1), next: day(2026, 1, 1)},
+		{expr: "2025-w53", wantErr: true},
+		{expr: "2026-w0", wantErr: true},
+		{expr: "2026-w100", wantErr: true},
+		{expr: "2026-q5", wantErr: true},
+		{expr: "2026-q3x", wantErr: true},
+		{expr: "2026-w37 ", start: day(2026, 9, 7), next: day(2026, 9, 14)}, // trimmed, not trailing text
+		{expr: "2026-w37-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			start, next, err := parseDay(tt.expr, testNow)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseDay(%q) = %v, %v, want an error", tt.expr, start, next)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseDay(%q): %v", tt.expr, err)
+			}
+			if !start.Equal(tt.start) || !next.Equal(tt.next) {
+				t.Errorf("parseDay(%q) = %v, %v, want %v, %v", tt.expr, start, next, tt.start, tt.next)
+			}
+		})
+	}
+}
+
+func TestStartOfPeriod(t *testing.T) {
+	tests := []struct {
+		name string
+		got  time.Time
+		want time.Time
+	}{
+		{"ISO week of a Friday", startOfISOWeek(testNow), day(2026, 10, 12)},
+		{"ISO week of a Sunday", startOfISOWeek(day(2026, 10, 25).Add(23 * time.Hour)), day(2026, 10, 19)},
+		{"ISO week of a Monday", startOfISOWeek(day(2026, 10, 19)), day(2026, 10, 19)},
+		{"ISO week across New Year", startOfISOWeek(day(2027, 1, 2)), day(2026, 12, 28)},
+		{"quarter of October", startOfQuarter(testNow), day(2026, 10, 1)},
+		{"quarter of March", startOfQuarter(day(2026, 3, 31)), day(2026, 1, 1)},
+		{"quarter of August", startOfQuarter(day(2026, 8, 15)), day(2026, 7, 1)},
+	}
+	for _, tt := range tests {
+		if !tt.got.Equal(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestSprintWindow(t *testing.T) {
+	tests := []struct {
+		name       string
+		sprint     Sprint
+		now        time.Time
+		back       int
+		start, end time.Time
+	}{
+		{
+			name:   "current sprint runs to now",
+			sprint: Sprint{Start: "2026-01-05", Length: 14},
+			now:    testNow,
+			start:  day(2026, 10, 12),
+			end:    testNow,
+		},
+		{
+			name:   "previous sprint ends where the current one starts",
+			sprint: Sprint{Start: "2026-01-05", Length: 14},
+			now:    testNow,
+			back:   1,
+			start:  day(2026, 9, 28),
+			end:    day(2026, 10, 12),
+		},
+		{
+			name:   "first day of a sprint",
+			sprint: Sprint{Start: "2026-01-05", Length: 14},
+			now:    day(2026, 10, 12).Add(time.Hour),
+			start:  day(2026, 10, 12),
+			end:    day(2026, 10, 12).Add(time.Hour),
+		},
+		{
+			name:   "anchor in the future rounds toward the past",
+			sprint: Sprint{Start: "2026-10-19", Length: 14},
+			now:    testNow,
+			start:  day(2026, 10, 5),
+			end:    testNow,
+		},
+		{
+			name:   "anchor a whole number of sprints ahead",
+			sprint: Sprint{Start: "2026-10-30", Length: 14},
+			now:    testNow,
+			start:  day(2026, 10, 16),
+			end:    testNow,
+		},
+		{
+			name:   "sprint across the end of summer time keeps midnight edges",
+			sprint: Sprint{Start: "2026-10-19", Length: 14},
+			now:    time.Date(2026, 11, 3, 12, 0, 0, 0, testZone),
+			back:   1,
+			start:  day(2026, 10, 19),
+			end:    day(2026, 11, 2),
+		},
+		{
+			name:   "just after midnight on the start of summer time",
+			sprint: Sprint{Start: "2026-03-15", Length: 14},
+			now:    time.Date(2026, 3, 29, 0, 30, 0, 0, testZone),
+			start:  day(2026, 3, 29),
+			end:    time.Date(2026, 3, 29, 0, 30, 0, 0, testZone),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.sprint.window(tt.now, tt.back)
+			if !start.Equal(tt.start) || !end.Equal(tt.end) {
+				t.Errorf("window = %v .. %v, want %v .. %v", start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestParseTimeframeSprint(t *testing.T) {
+	sprint := &Sprint{Start: "2026-01-05", Length: 14}
+
+	config, err := parseTimeframe("sprint-2", testNow, sprint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.TimeMode != timeModeSprint || config.SprintsBack != 2 || config.Sprint != sprint {
+		t.Errorf("sprint-2 = %+v", config)
+	}
+
+	for _, tt := range []struct {
+		value  string
+		sprint *Sprint
+	}{
+		{"sprint", nil},
+		{"sprint2", sprint},
+		{"sprint-x", sprint},
+		{"sprint--1", sprint},
+		{"sprint", &Sprint{Start: "2026-01-05"}},
+		{"sprint", &Sprint{Start: "January", Length: 14}},
+	} {
+		if config, err := parseTimeframe(tt.value, testNow, tt.sprint); err == nil {
+			t.Errorf("parseTimeframe(%q, %+v) = %+v, want an error", tt.value, tt.sprint, config)
+		}
+	}
+}
+
+func TestWindowAt(t *testing.T) {
+	lastRun := time.Date(2026, 10, 15, 7, 0, 0, 0, time.UTC)
+	tests := []struct {
+		config     ReportConfig
+		start, end time.Time
+	}{
+		{ReportConfig{TimeMode: timeModeLastWeek}, testNow.AddDate(0, 0, -7), testNow},
+		{ReportConfig{TimeMode: timeModeLastMonth}, day(2026, 9, 1), day(2026, 10, 1)},
+		{ReportConfig{TimeMode: timeModeThisMonthToDate}, day(2026, 10, 1), testNow},
+		{ReportConfig{TimeMode: timeModeLast4Weeks}, testNow.AddDate(0, 0, -28), testNow},
+		{ReportConfig{TimeMode: timeModeLast30Days}, testNow.AddDate(0, 0, -30), testNow},
+		{ReportConfig{TimeMode: timeModePrevWeek}, day(2026, 10, 5), day(2026, 10, 12)},
+		{ReportConfig{TimeMode: timeModeThisWeek}, day(2026, 10, 12), testNow},
+		{ReportConfig{TimeMode: timeModePrevQuarter}, day(2026, 7, 1), day(2026, 10, 1)},
+		{ReportConfig{TimeMode: timeModeThisQuarter}, day(2026, 10, 1), testNow},
+		{ReportConfig{TimeMode: timeModeYearToDate}, day(2026, 1, 1), testNow},
+		{ReportConfig{TimeMode: timeModePrevYear}, day(2025, 1, 1), day(2026, 1, 1)},
+		{ReportConfig{TimeMode: timeModeSprint, Sprint: &Sprint{Start: "2026-01-05", Length: 14}, SprintsBack: 1}, day(2026, 9, 28), day(2026, 10, 12)},
+		{ReportConfig{TimeMode: timeModeSinceLast}, testNow.AddDate(0, 0, -firstRunDays), testNow},
+		{ReportConfig{TimeMode: timeModeSinceLast, From: lastRun}, lastRun, testNow},
+		{customConfig(day(2026, 9, 1), day(2026, 9, 8)), day(2026, 9, 1), day(2026, 9, 8)},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.config.TimeMode), func(t *testing.T) {
+			tt.config.Location = testZone
+			// now given in another zone must still be counted in the configured one
+			window := windowAt(tt.config, testNow.UTC())
+			if !window.Start.Equal(tt.start) || !window.End.Equal(tt.end) {
+				t.Errorf("window = %v .. %v, want %v .. %v", window.Start, window.End, tt.start, tt.end)
+			}
+			if window.Start.Location() != testZone || window.End.Location() != testZone {
+				t.Errorf("window not in the configured zone: %v .. %v", window.Start, window.End)
+			}
+			if window.Zone != "Europe/Madrid" {
+				t.Errorf("Zone = %q", window.Zone)
+			}
+		})
+	}
+}
+
+func TestWindowAtCountsDaysInItsZone(t *testing.T) {
+	// 23:30 on September 30th in UTC is already October 1st in Madrid
+	now := time.Date(2026, 9, 30, 23, 30, 0, 0, time.UTC)
+
+	utc := windowAt(ReportConfig{TimeMode: timeModeLastMonth, Location: time.UTC}, now)
+	if want := time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC); !utc.Start.Equal(want) {
+		t.Errorf("UTC last month starts %v, want %v", utc.Start, want)
+	}
+
+	madrid := windowAt(ReportConfig{TimeMode: timeModeLastMonth, Location: testZone}, now)
+	if !madrid.Start.Equal(day(2026, 9, 1)) || !madrid.End.Equal(day(2026, 10, 1)) {
+		t.Errorf("Madrid last month = %v .. %v, want September", madrid.Start, madrid.End)
+	}
+}
+
+func TestTimeWindowEdges(t *testing.T) {
+	window := windowAt(ReportConfig{TimeMode: timeModePrevWeek, Location: testZone}, testNow)
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"first moment", day(2026, 10, 5), true},
+		{"last moment", day(2026, 10, 12).Add(-time.Nanosecond), true},
+		{"end, the next week's first moment", day(2026, 10, 12), false},
+		{"just before the start", day(2026, 10, 5).Add(-time.Nanosecond), false},
+		{"start given in UTC", day(2026, 10, 5).UTC(), true},
+	}
+	for _, tt := range tests {
+		if got := window.contains(tt.t); got != tt.want {
+			t.Errorf("contains(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got := window.lastDay(); got != "2026-10-11" {
+		t.Errorf("lastDay = %s, want the Sunday", got)
+	}
+
+	// Consecutive windows share an edge, so nothing falls between them or into both
+	this := windowAt(ReportConfig{TimeMode: timeModeThisWeek, Location: testZone}, testNow)
+	if !window.End.Equal(this.Start) {
+		t.Errorf("previous week ends %v, this week starts %v", window.End, this.Start)
+	}
+}
+
+func TestReportLocation(t *testing.T) {
+	profile := &Profile{Timezone: "America/New_York"}
+	tests := []struct {
+		name                string
+		flag, configuration string
+		profile             *Profile
+		want                string // empty for the machine's zone
+		wantErr             bool
+	}{
+		{name: "machine zone by default"},
+		{name: "config", configuration: "UTC", want: "UTC"},
+		{name: "profile over config", configuration: "UTC", profile: profile, want: "America/New_York"},
+		{name: "flag over profile", flag: "Europe/Madrid", configuration: "UTC", profile: profile, want: "Europe/Madrid"},
+		{name: "unknown zone", flag: "Mars/Olympus", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, err := reportLocation(tt.flag, tt.profile, tt.configuration)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("reportLocation = %v, want an error", location)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" && location != time.Local {
+				t.Errorf("reportLocation = %s, want the machine's zone", location)
+			}
+			if tt.want != "" && location.String() != tt.want {
+				t.Errorf("reportLocation = %s, want %s", location, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The timeline splits a report's window into days, or into ISO weeks once the window is longer than
+// timelineDayLimit days, and counts the versions and comments in each, so a burst on Friday reads
+// differently from steady work. Buckets follow the window's zone and are clipped to the window.
+const timelineDayLimit = 35
+
+type TimelineBucket struct {
+	Start    time.Time
+	End      time.Time
+	Versions int
+	Comments int
+}
+
+func (b TimelineBucket) events() int {
+	return b.Versions + b.Comments
+}
+
+// timelineWeekly reports whether the window is bucketed by week rather than by day
+func timelineWeekly(window TimeWindow) bool {
+	return window.End.Sub(window.Start) > timelineDayLimit*24*time.Hour
+}
+
+// buildTimeline counts the files' versions and comments per bucket. files is the flat list, branches included.
+func buildTimeline(window TimeWindow, files []FileActivity) []TimelineBucket {
+	if !window.Start.Before(window.End) {
+		return nil
+	}
+
+	weekly := timelineWeekly(window)
+	var buckets []TimelineBucket
+	start := window.Start
+	for start.Before(window.End) {
+		// Step in calendar days so a DST change doesn't shift the edges
+		day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+		next := day.AddDate(0, 0, 1)
+		if weekly {
+			next = startOfISOWeek(start).AddDate(0, 0, 7)
+		}
+		if next.After(window.End) {
+			next = window.End
+		}
+		buckets = append(buckets, TimelineBucket{Start: start, End: next})
+		start = next
+	}
+
+	bucketOf := func(t time.Time) int {
+		for i, bucket := range buckets {
+			if !t.Before(bucket.Start) && t.Before(bucket.End) {
+				return i
+			}
+		}
+		return -1
+	}
+	for _, file := range files {
+		for _, version := range file.Versions {
+			if i := bucketOf(version.Created); i >= 0 {
+				buckets[i].Versions++
+			}
+		}
+		for _, comment := range file.Comments {
+			if i := bucketOf(comment.CreatedAt); i >= 0 {
+				buckets[i].Comments++
+			}
+		}
+	}
+	return buckets
+}
+
+// timelineLabel names a bucket: "Fri 2026-10-09" for days, "Week of 2026-10-05" for weeks
+func timelineLabel(bucket TimelineBucket, weekly bool) string {
+	if weekly {
+		return "Week of " + bucket.Start.Format("2006-01-02")
+	}
+	return bucket.Start.Format("Mon 2006-01-02")
+}
+
+// timelineBar draws n events as a bar of at most width blocks, scaled to the busiest bucket
+func timelineBar(n, busiest, width int) string {
+	if n == 0 || busiest == 0 {
+		return ""
+	}
+	blocks := n * width / busiest
+	if blocks == 0 {
+		blocks = 1
+	}
+	return strings.Repeat("█", blocks)
+}
+
+// sparkline draws one character per bucket, from ▁ for the quietest active bucket to █ for the busiest
+func sparkline(buckets []TimelineBucket) string {
+	levels := []rune("▁▂▃▄▅▆▇█")
+	busiest := 0
+	for _, bucket := range buckets {
+		busiest = max(busiest, bucket.events())
+	}
+
+	var sb strings.Builder
+	for _, bucket := range buckets {
+		if bucket.events() == 0 {
+			sb.WriteRune('·')
+		} else {
+			sb.WriteRune(levels[(bucket.events()*len(levels)-1)/busiest])
+		}
+	}
+	return sb.String()
+}
+
+// busiestBucket returns the bucket with the most events, the earliest on a tie
+func busiestBucket(buckets []TimelineBucket) (TimelineBucket, bool) {
+	var busiest TimelineBucket
+	found := false
+	for _, bucket := range buckets {
+		if bucket.events() > busiest.events() {
+			busiest = bucket
+			found = true
+		}
+	}
+	return busiest, found
+}
+
+// writeTimelineMarkdown writes the timeline as a table with a bar per bucket
+func writeTimelineMarkdown(sb *strings.Builder, report *ActivityReport) {
+	weekly := timelineWeekly(report.TimeWindow)
+	busiest, found := busiestBucket(report.Timeline)
+	if !found {
+		return
+	}
+
+	unit, column := "day", "Day"
+	if weekly {
+		unit, column = "week", "Week"
+	}
+	sb.WriteString(fmt.Sprintf("\n### Activity by %s\n\n", unit))
+	sb.WriteString(fmt.Sprintf("| %s | Versions | Comments | |\n", column))
+	sb.WriteString("|---|---:|---:|---|\n")
+	for _, bucket := range report.Timeline {
+		sb.WriteString(fmt.Sprintf("| %s | %d | %d | %s |\n",
+			timelineLabel(bucket, weekly), bucket.Versions, bucket.Comments, timelineBar(bucket.events(), busiest.events(), 20)))
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Watched files are scanned directly, without listing the projects they live in.
+// They are reported under this heading since the API doesn't say which project a file is in.
+const watchedFilesProject = "Watched files"
+
+var fileKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// Path segments that precede the file key in Figma URLs
+var fileURLKinds = map[string]bool{
+	"file":   true,
+	"design": true,
+	"proto":  true,
+	"board":  true,
+	"slides": true,
+	"deck":   true,
+}
+
+// parseFileKey accepts a file key or a Figma file URL, e.g. https://www.figma.com/design/<key>/<name>?node-id=1-2.
+// A branch URL (…/design/<key>/branch/<branch key>/…) yields the branch's key.
+func parseFileKey(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return "", fmt.Errorf("empty file key")
+	}
+
+	if !strings.Contains(input, "/") {
+		if !fileKeyPattern.MatchString(input) {
+			return "", fmt.Errorf("'%s' is not a Figma file key or URL", input)
+		}
+		return input, nil
+	}
+
+	raw := input
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Host != "figma.com" && !strings.HasSuffix(u.Host, ".figma.com")) {
+		return "", fmt.Errorf("'%s' is not a Figma file URL", input)
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		if !fileURLKinds[segments[i]] {
+			continue
+		}
+		key := segments[i+1]
+		if i+3 < len(segments) && segments[i+2] == "branch" {
+			key = segments[i+3]
+		}
+		if fileKeyPattern.MatchString(key) {
+			return key, nil
+		}
+		break
+	}
+	return "", fmt.Errorf("no file key in '%s'", input)
+}
+
+// parseFileKeys parses a comma-separated list of file keys and URLs, dropping duplicates
+func parseFileKeys(value string) ([]string, error) {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, item := range splitList(value) {
+		key, err := parseFileKey(item)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// withFiles returns a copy of the profile that also watches the given file keys
+func (p Profile) withFiles(keys []string) *Profile {
+	watched := make(map[string]bool)
+	for _, file := range p.Files {
+		watched[file.Key] = true
+	}
+
+	p.Files = append([]ProfileFile(nil), p.Files...)
+	for _, key := range keys {
+		if !watched[key] {
+			watched[key] = true
+			p.Files = append(p.Files, ProfileFile{Key: key, Name: key})
+		}
+	}
+	return &p
+}
+
+// lookupFile checks that a file pasted into the wizard exists and fetches its name
+func lookupFile(client *FigmaClient, input string) tea.Cmd {
+	return func() tea.Msg {
+		key, err := parseFileKey(input)
+		if err != nil {
+			return fileLookupErrMsg{err: err.Error()}
+		}
+		if !client.hasCredentials() {
+			return fileLookupErrMsg{err: "No Figma token set"}
+		}
+
+		meta, err := client.FileMeta(context.Background(), key, time.Time{})
+		if err != nil {
+			return fileLookupErrMsg{err: fmt.Sprintf("file %s: %s", key, err.Error())}
+		}
+
+		return fileLookupMsg{file: ProfileFile{Key: key, Name: meta.Name}}
+	}
+}